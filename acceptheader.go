@@ -1,11 +1,5 @@
 package negotiator
 
-import (
-	"sort"
-	"strconv"
-	"strings"
-)
-
 const (
 	// ParameteredMediaRangeWeight is the default weight of a media range with an
 	// accept-param
@@ -26,79 +20,69 @@ type accept string
 
 // MediaRanges returns prioritized media ranges
 func (accept accept) ParseMediaRanges() []weightedValue {
-	var retVals []weightedValue
-	mrs := strings.Split(string(accept), ",")
-
-	for _, mr := range mrs {
-		mrAndAcceptParam := strings.Split(mr, ";")
-		//if no accept-param
-		if len(mrAndAcceptParam) == 1 {
-			retVals = append(retVals, handleMediaRangeNoAcceptParams(mrAndAcceptParam[0]))
-			continue
-		}
+	var buf [acceptBufferSize]MediaRange
+	ranges := accept.prioritizedMediaRanges(buf[:0])
 
-		retVals = append(retVals, handleMediaRangeWithAcceptParams(mrAndAcceptParam[0], mrAndAcceptParam[1:]))
+	retVals := make([]weightedValue, len(ranges))
+	for i, mr := range ranges {
+		retVals[i] = weightedValue{legacyValue(mr), legacyWeight(mr)}
 	}
 
+	return retVals
+}
+
+// prioritizedMediaRanges parses the accept header, discarding malformed media
+// ranges, and orders what remains by descending weight. The media ranges are appended
+// to dst, with their parameters kept as text (see ScanAccept).
+func (accept accept) prioritizedMediaRanges(dst []MediaRange) []MediaRange {
+	ranges, _ := appendMediaRanges(dst, string(accept), ParseLimits{}, true, nil)
+
 	//If no Accept header field is present, then it is assumed that the client
 	//accepts all media types. If an Accept header field is present, and if the
 	//server cannot send a response which is acceptable according to the combined
 	//Accept field value, then the server SHOULD send a 406 (not acceptable)
 	//response.
-	sort.Sort(byWeight(retVals))
+	sortByLegacyWeight(ranges)
 
-	return retVals
+	return ranges
 }
 
-func handleMediaRangeWithAcceptParams(mediaRange string, acceptParams []string) weightedValue {
-	wv := new(weightedValue)
-	wv.Value = strings.TrimSpace(mediaRange)
-	wv.Weight = ParameteredMediaRangeWeight
-
-	for index := 0; index < len(acceptParams); index++ {
-		ap := strings.ToLower(acceptParams[index])
-		if isQualityAcceptParam(ap) {
-			wv.Weight = parseQuality(ap)
-		} else {
-			wv.Value = strings.Join([]string{wv.Value, acceptParams[index]}, ";")
+// sortByLegacyWeight orders media ranges by descending weight, keeping the order of
+// those with equal weight. An insertion sort does not allocate, unlike sort.Stable, and
+// suits the handful of ranges in an Accept header.
+func sortByLegacyWeight(ranges []MediaRange) {
+	for i := 1; i < len(ranges); i++ {
+		for j := i; j > 0 && legacyWeight(ranges[j]) > legacyWeight(ranges[j-1]); j-- {
+			ranges[j], ranges[j-1] = ranges[j-1], ranges[j]
 		}
 	}
-	return *wv
-}
-
-func isQualityAcceptParam(acceptParam string) bool {
-	return strings.Contains(acceptParam, "q=")
 }
 
-func parseQuality(acceptParam string) float64 {
-	weight, err := strconv.ParseFloat(strings.SplitAfter(acceptParam, "q=")[1], 64)
-	if err != nil {
-		weight = 1.0
+// legacyValue gives the media range with its parameters and accept extensions,
+// but without its quality.
+func legacyValue(mr MediaRange) string {
+	extensions := mr.Extensions
+	if mr.extensions != "" {
+		extensions = textParamMap(mr.extensions, true)
 	}
-	return weight
+	return mr.String() + formatParams(extensions, true)
 }
 
-func handleMediaRangeNoAcceptParams(mediaRange string) weightedValue {
-	wv := new(weightedValue)
-	wv.Value = strings.TrimSpace(mediaRange)
-	wv.Weight = 0.0
-
-	typeSubtype := strings.Split(wv.Value, "/")
-	if len(typeSubtype) == 2 {
-		switch {
-		//a type of * with a non-star subtype is invalid, so if the type is
-		//star the assume that the subtype is too
-		case typeSubtype[0] == "*": //&& typeSubtype[1] == "*":
-			wv.Weight = StarStarMediaRangeWeight
-			break
-		case typeSubtype[1] == "*":
-			wv.Weight = TypeStarMediaRangeWeight
-			break
-		case typeSubtype[1] != "*":
-			wv.Weight = TypeSubtypeMediaRangeWeight
-			break
-		}
-	} //else invalid media range the weight remains 0.0
+// legacyWeight gives the weight of a media range, using the explicit quality
+// if there is one and otherwise a default weight according to its precedence.
+func legacyWeight(mr MediaRange) float64 {
+	if mr.weighted || mr.paramCount() > 0 {
+		return mr.Q
+	}
 
-	return *wv
+	switch {
+	//a type of * with a non-star subtype is invalid, so if the type is
+	//star the assume that the subtype is too
+	case mr.Type == "*":
+		return StarStarMediaRangeWeight
+	case mr.Subtype == "*":
+		return TypeStarMediaRangeWeight
+	default:
+		return TypeSubtypeMediaRangeWeight
+	}
 }
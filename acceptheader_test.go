@@ -156,3 +156,14 @@ func TestMediaRanges_should_handle_precedence4(t *testing.T) {
 	assert.Equal(t, "text/*", mr[4].Value)
 	assert.Equal(t, 0.3, mr[4].Weight)
 }
+
+func TestMediaRanges_should_not_split_quoted_strings(t *testing.T) {
+	a := accept(`text/html;foo="a,b;q=0.1", text/plain;q=0.5`)
+	mr := a.ParseMediaRanges()
+
+	assert.Equal(t, 2, len(mr))
+	assert.Equal(t, `text/html;foo="a,b;q=0.1"`, mr[0].Value)
+	assert.Equal(t, 1.0, mr[0].Weight)
+	assert.Equal(t, "text/plain", mr[1].Value)
+	assert.Equal(t, 0.5, mr[1].Weight)
+}
@@ -0,0 +1,106 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Accept headers sent by common clients.
+var benchmarkAccepts = []struct {
+	name   string
+	accept string
+}{
+	{"Chrome", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+	{"Firefox", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+	{"Curl", "*/*"},
+	{"APIClient", "application/json"},
+	{"APIClientWeighted", "application/json, application/xml;q=0.9, */*;q=0.1"},
+}
+
+func BenchmarkParseAccept(b *testing.B) {
+	for _, bm := range benchmarkAccepts {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ParseAccept(bm.accept)
+			}
+		})
+	}
+}
+
+func BenchmarkScanAccept(b *testing.B) {
+	for _, bm := range benchmarkAccepts {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ScanAccept(bm.accept, DefaultParseLimits, func(MediaRange) bool { return true })
+			}
+		})
+	}
+}
+
+func BenchmarkParseMediaRanges(b *testing.B) {
+	for _, bm := range benchmarkAccepts {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				accept(bm.accept).ParseMediaRanges()
+			}
+		})
+	}
+}
+
+func BenchmarkDecide(b *testing.B) {
+	modes := []struct {
+		name string
+		mode Mode
+	}{{"Legacy", LegacyMode}, {"RFC9110", RFC9110Mode}, {"Unified", UnifiedMode}}
+
+	for _, m := range modes {
+		n := New(NewJSON(), NewXML()).WithMode(m.mode)
+		for _, bm := range benchmarkAccepts {
+			b.Run(m.name+"/"+bm.name, func(b *testing.B) {
+				req, _ := http.NewRequest("GET", "/", nil)
+				req.Header.Set("Accept", bm.accept)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					n.Decide(req)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecideCached(b *testing.B) {
+	n := New(NewJSON(), NewXML()).WithDecisionCache(100)
+	for _, bm := range benchmarkAccepts {
+		b.Run(bm.name, func(b *testing.B) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", bm.accept)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n.Decide(req)
+			}
+		})
+	}
+}
+
+func BenchmarkNegotiate(b *testing.B) {
+	for _, bm := range benchmarkAccepts {
+		b.Run(bm.name, func(b *testing.B) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", bm.accept)
+			recorder := httptest.NewRecorder()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				recorder.HeaderMap = make(http.Header)
+				recorder.Body.Reset()
+				Negotiate(recorder, req, "Joe Bloggs")
+			}
+		})
+	}
+}
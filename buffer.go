@@ -0,0 +1,96 @@
+package negotiator
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which a buffer is not returned to the pool,
+// so that one very large response does not pin its memory for good.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// WithBuffering turns buffered rendering on or off. When it is on, the response
+// processor renders into a pooled buffer, and the status, headers, Content-Length and
+// body are only sent once it has succeeded. If it fails, nothing it wrote is sent and
+// Negotiate returns its error, so that the handler can still send a proper error
+// response, for instance with Error. A new Negotiator is returned with the same
+// processors as the original.
+func (n *Negotiator) WithBuffering(enabled bool) *Negotiator {
+	return n.With(WithBuffering(enabled))
+}
+
+// bufferedWriter holds back the status, headers and body of a response until commit.
+type bufferedWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	buf    *bytes.Buffer
+}
+
+func newBufferedWriter(w http.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{w: w, header: w.Header().Clone(), buf: bufferPool.Get().(*bytes.Buffer)}
+}
+
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.status == 0 {
+		bw.status = code
+	}
+}
+
+func (bw *bufferedWriter) Write(p []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.buf.Write(p)
+}
+
+// commit sends the response that was held back.
+func (bw *bufferedWriter) commit() error {
+	defer bw.release()
+
+	header := bw.w.Header()
+	for name := range header {
+		if _, ok := bw.header[name]; !ok {
+			delete(header, name)
+		}
+	}
+	for name, values := range bw.header {
+		header[name] = values
+	}
+
+	status := bw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if !bodyAllowed(status) {
+		bw.w.WriteHeader(status)
+		return nil
+	}
+
+	header.Set("Content-Length", strconv.Itoa(bw.buf.Len()))
+	bw.w.WriteHeader(status)
+	_, err := bw.w.Write(bw.buf.Bytes())
+	return err
+}
+
+// release returns the buffer to the pool, discarding what was held back.
+func (bw *bufferedWriter) release() {
+	if bw.buf == nil {
+		return
+	}
+	if bw.buf.Cap() <= maxPooledBufferSize {
+		bw.buf.Reset()
+		bufferPool.Put(bw.buf)
+	}
+	bw.buf = nil
+}
@@ -0,0 +1,135 @@
+package negotiator
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferingShouldSetContentLength(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+
+	err := New(NewJSON()).WithBuffering(true).Negotiate(recorder, req, map[string]string{"name": "Joe"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "15", recorder.HeaderMap.Get("Content-Length"))
+	assert.Equal(t, `{"name":"Joe"}`+"\n", recorder.Body.String())
+}
+
+func TestBufferingShouldSendNothingWhenEncodingFails(t *testing.T) {
+	var processors = []ResponseProcessor{NewJSONIndent2Spaces(), NewJSON(), NewCSV()}
+
+	for _, processor := range processors {
+		req, _ := http.NewRequest("GET", "/", nil)
+		recorder := httptest.NewRecorder()
+		negotiator := New(processor).WithBuffering(true)
+
+		err := negotiator.Negotiate(recorder, req, []interface{}{1, math.Inf(1)})
+
+		assert.Error(t, err)
+		assert.False(t, recorder.Flushed)
+		assert.Empty(t, recorder.Body.String())
+		assert.Empty(t, recorder.HeaderMap.Get("Content-Type"))
+		assert.Contains(t, recorder.HeaderMap.Get("Vary"), "Accept")
+
+		negotiator.Error(recorder, req, err)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, "application/problem+json", recorder.HeaderMap.Get("Content-Type"))
+	}
+}
+
+func TestBufferingShouldWorkWithStatusCharsetAndCompression(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Accept-Charset", "utf-16le")
+	recorder := httptest.NewRecorder()
+
+	negotiator := New(NewTXT()).WithBuffering(true).WithCharsets(CharsetUTF16LE).WithCompression(0)
+	err := negotiator.Respond(recorder, req, ResponseOptions{Status: http.StatusCreated}, "Joe")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "gzip", recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; charset=utf-16le", recorder.HeaderMap.Get("Content-Type"))
+	assert.NotEmpty(t, recorder.HeaderMap.Get("Content-Length"))
+	assert.Equal(t, "J\x00o\x00e\x00\n\x00", decompress(t, "gzip", recorder.Body))
+}
+
+func TestBufferingShouldOmitContentLengthForNoContent(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	New(NewJSON()).WithBuffering(true).Negotiate(recorder, req, nil)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.HeaderMap.Get("Content-Length"))
+}
+
+type failingProcessor struct{ fakeProcessor }
+
+func (*failingProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("X-Partial", "yes")
+	w.Write([]byte("<html>half"))
+	return errors.New("template failed")
+}
+
+func TestBufferingShouldDiscardHeadersOfFailedRender(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/negotiatortesting")
+	recorder := httptest.NewRecorder()
+
+	err := New(&failingProcessor{}).WithBuffering(true).Negotiate(recorder, req, "foo")
+
+	assert.EqualError(t, err, "template failed")
+	assert.Empty(t, recorder.HeaderMap.Get("X-Partial"))
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestBufferingShouldDiscardResponseOptionsOfFailedRender(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "de")
+	recorder := httptest.NewRecorder()
+	negotiator := New(NewJSON()).WithBuffering(true).WithLanguages("en", "de")
+
+	err := negotiator.Respond(recorder, req, ResponseOptions{
+		Status: http.StatusCreated,
+		Header: http.Header{"Location": {"/x/1"}},
+	}, math.Inf(1))
+
+	assert.Error(t, err)
+	assert.Empty(t, recorder.HeaderMap.Get("Location"))
+	assert.Empty(t, recorder.HeaderMap.Get("Content-Language"))
+
+	negotiator.Error(recorder, req, err)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Empty(t, recorder.HeaderMap.Get("Location"))
+	assert.Empty(t, recorder.HeaderMap.Get("Content-Language"))
+}
+
+func TestBufferingShouldSendResponseOptionsOfSuccessfulRender(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "de")
+	recorder := httptest.NewRecorder()
+	negotiator := New(NewJSON()).WithBuffering(true).WithLanguages("en", "de")
+
+	err := negotiator.Respond(recorder, req, ResponseOptions{
+		Status: http.StatusCreated,
+		Header: http.Header{"Location": {"/x/1"}},
+	}, "Joe")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "/x/1", recorder.HeaderMap.Get("Location"))
+	assert.Equal(t, "de", recorder.HeaderMap.Get("Content-Language"))
+}
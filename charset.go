@@ -0,0 +1,297 @@
+package negotiator
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The charsets that a Negotiator can transcode responses into. Response processors
+// always write UTF-8.
+const (
+	CharsetUTF8    = "utf-8"
+	CharsetUTF16   = "utf-16" // big-endian, always with a byte order mark
+	CharsetUTF16BE = "utf-16be"
+	CharsetUTF16LE = "utf-16le"
+	CharsetLatin1  = "iso-8859-1"
+)
+
+// WithCharsets sets the charsets in which responses are available, from those above;
+// the first is the default. The Negotiator then chooses a charset for every text or XML
+// response from the Accept-Charset header, adds Accept-Charset to Vary, transcodes the
+// output of the response processor and adds the charset parameter to its Content-Type.
+// If the client accepts none of the charsets, the response is 406 (Not Acceptable).
+// Other media types, JSON among them, are sent as the processor writes them, whatever
+// the Accept-Charset header. Where no media type was matched, as with the fallback
+// processor, those that the processor declares decide; a processor that declares none
+// is taken to write text. Unknown charsets are ignored. A new Negotiator is returned with the same processors as the
+// original.
+func (n *Negotiator) WithCharsets(charsets ...string) *Negotiator {
+	return n.With(WithCharsets(charsets...))
+}
+
+// WithByteOrderMark chooses whether responses transcoded into UTF-8, UTF-16BE or
+// UTF-16LE begin with a byte order mark. UTF-16 always has one and ISO-8859-1 never
+// does. A new Negotiator is returned with the same processors as the original.
+func (n *Negotiator) WithByteOrderMark(enabled bool) *Negotiator {
+	return n.With(WithByteOrderMark(enabled))
+}
+
+// negotiateCharset chooses the charset for the request, if the Negotiator has any and
+// the chosen media type is transcoded. It returns false if none of them is acceptable.
+// The Negotiator's order breaks ties.
+func (n *Negotiator) negotiateCharset(req *http.Request, d *Decision) bool {
+	if len(n.charsets) == 0 {
+		return true
+	}
+	if !transcodes(d.Processor, d.MediaType) {
+		if d.explain {
+			d.reason("no charset for processor %d", d.Index)
+		}
+		return true
+	}
+
+	charset, q := n.bestCharset(req, d)
+	switch {
+	case q == 0:
+		d.reason("no acceptable charset")
+		return false
+	case !d.explain:
+	case combinedHeader(req.Header, "Accept-Charset") == "":
+		d.reason("no Accept-Charset header; chose charset %s", charset)
+	default:
+		d.reason("chose charset %s with q=%g", charset, q)
+	}
+	d.Charset = charset
+	return true
+}
+
+// bestCharset returns the charset with the highest quality, the first winning ties, or
+// the empty string with quality 1 if the Negotiator has no charsets. Without an
+// Accept-Charset header the first charset is chosen. Both modes choose the charset
+// with it.
+func (n *Negotiator) bestCharset(req *http.Request, d *Decision) (string, float64) {
+	if len(n.charsets) == 0 {
+		return "", 1.0
+	}
+
+	d.Vary = append(d.Vary, "Accept-Charset")
+	acceptCharset := combinedHeader(req.Header, "Accept-Charset")
+	if acceptCharset == "" {
+		return n.charsets[0], 1.0
+	}
+
+	best, bestQ := "", 0.0
+	for _, charset := range n.charsets {
+		if q := charsetQuality(acceptCharset, charset); q > bestQ {
+			best, bestQ = charset, q
+		}
+	}
+	return best, bestQ
+}
+
+// charsetQuality gives the client's quality for the charset: that of the range naming
+// it, or failing that of "*", or 0.
+func charsetQuality(acceptCharset, charset string) float64 {
+	wildcard := 0.0
+	for _, cr := range parseWeightedList(acceptCharset) {
+		switch {
+		case strings.EqualFold(cr.Value, charset):
+			return cr.Weight
+		case cr.Value == "*" && wildcard == 0:
+			wildcard = cr.Weight
+		}
+	}
+	return wildcard
+}
+
+// bareMediaType returns the media type of a Content-Type without its parameters. Unlike
+// strings.Split, it does not allocate.
+func bareMediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// isTranscodedType tests whether responses of the media type are transcoded into the
+// negotiated charset: text and XML are, whereas JSON is always UTF-8 (RFC 8259) and
+// other types are not text at all.
+func isTranscodedType(contentType string) bool {
+	mediaType := strings.ToLower(bareMediaType(contentType))
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// transcodes tests whether the responses of a processor are transcoded into the
+// negotiated charset: those of the chosen media type if there is one, or otherwise
+// those of the media types the processor declares. A processor that declares none, or
+// no processor at all, is taken to write text, as only its Content-Type can tell.
+func transcodes(processor ResponseProcessor, mediaType string) bool {
+	if mediaType != "" {
+		return isTranscodedType(mediaType)
+	}
+	if processor == nil {
+		return true
+	}
+
+	offers := offersOf(processor)
+	for _, offer := range offers {
+		if isTranscodedType(offer.MediaType()) {
+			return true
+		}
+	}
+	return len(offers) == 0
+}
+
+// runeEncoder appends the encoding of a rune to dst.
+type runeEncoder func(dst []byte, r rune) []byte
+
+func encoderFor(charset string) runeEncoder {
+	switch charset {
+	case CharsetUTF8:
+		return utf8.AppendRune
+	case CharsetUTF16, CharsetUTF16BE:
+		return func(dst []byte, r rune) []byte { return appendUTF16(dst, r, false) }
+	case CharsetUTF16LE:
+		return func(dst []byte, r rune) []byte { return appendUTF16(dst, r, true) }
+	case CharsetLatin1:
+		return func(dst []byte, r rune) []byte {
+			if r > 0xFF {
+				r = '?'
+			}
+			return append(dst, byte(r))
+		}
+	}
+	return nil
+}
+
+func appendUTF16(dst []byte, r rune, littleEndian bool) []byte {
+	var units [2]uint16
+	n := 1
+	if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+		units[0], units[1] = uint16(r1), uint16(r2)
+		n = 2
+	} else {
+		units[0] = uint16(r)
+	}
+
+	for _, u := range units[:n] {
+		if littleEndian {
+			dst = append(dst, byte(u), byte(u>>8))
+		} else {
+			dst = append(dst, byte(u>>8), byte(u))
+		}
+	}
+	return dst
+}
+
+// charsetWriter transcodes the UTF-8 written by a response processor into another
+// charset, if the Content-Type is text or XML.
+type charsetWriter struct {
+	http.ResponseWriter
+	charset     string
+	encode      runeEncoder
+	bom         bool
+	wroteHeader bool
+	passThrough bool   // the Content-Type is not transcoded
+	pending     []byte // an incomplete UTF-8 sequence from the end of the last write
+	buf         []byte
+}
+
+// newCharsetWriter wraps w to transcode into the charset. For UTF-8 without a byte order
+// mark, only the Content-Type is changed.
+func newCharsetWriter(w http.ResponseWriter, charset string, bom bool) *charsetWriter {
+	if charset == CharsetUTF16 {
+		bom = true
+	} else if charset == CharsetLatin1 {
+		bom = false
+	}
+	return &charsetWriter{ResponseWriter: w, charset: charset, encode: encoderFor(charset), bom: bom}
+}
+
+func (cw *charsetWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if isTranscodedType(cw.Header().Get("Content-Type")) {
+			setCharsetParam(cw.Header(), cw.charset)
+			cw.Header().Del("Content-Length")
+		} else {
+			cw.passThrough = true
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *charsetWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.passThrough {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	if cw.bom {
+		cw.bom = false
+		if _, err := cw.ResponseWriter.Write(cw.encode(nil, '\uFEFF')); err != nil {
+			return 0, err
+		}
+	}
+
+	if cw.charset == CharsetUTF8 {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	data := p
+	if len(cw.pending) > 0 {
+		data = append(cw.pending, p...)
+		cw.pending = nil
+	}
+
+	out := cw.buf[:0]
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			cw.pending = append([]byte(nil), data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		out = cw.encode(out, r)
+		data = data[size:]
+	}
+	cw.buf = out
+
+	if _, err := cw.ResponseWriter.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// close writes out any incomplete UTF-8 sequence left at the end of the response.
+func (cw *charsetWriter) close() error {
+	if len(cw.pending) == 0 {
+		return nil
+	}
+	cw.pending = nil
+	_, err := cw.ResponseWriter.Write(cw.encode(nil, utf8.RuneError))
+	return err
+}
+
+// setCharsetParam sets the charset parameter of the Content-Type header, if there is one.
+func setCharsetParam(header http.Header, charset string) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		return
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return
+	}
+
+	params["charset"] = charset
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+}
@@ -0,0 +1,198 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldNegotiateCharset(t *testing.T) {
+	var charsetTests = []struct {
+		acceptCharset string
+		contentType   string
+		body          string
+	}{
+		{"", "text/plain; charset=utf-8", "Zoë\n"},
+		{"utf-16le", "text/plain; charset=utf-16le", "Z\x00o\x00\xeb\x00\n\x00"},
+		{"UTF-16BE;q=0.9, iso-8859-1;q=0.5", "text/plain; charset=utf-16be", "\x00Z\x00o\x00\xeb\x00\n"},
+		{"utf-16", "text/plain; charset=utf-16", "\xfe\xff\x00Z\x00o\x00\xeb\x00\n"},
+		{"iso-8859-1, *;q=0.1", "text/plain; charset=iso-8859-1", "Zo\xeb\n"},
+		{"*", "text/plain; charset=utf-8", "Zoë\n"},
+		{"utf-8;q=0, *", "text/plain; charset=utf-16", "\xfe\xff\x00Z\x00o\x00\xeb\x00\n"},
+	}
+
+	negotiator := New(NewTXT()).WithCharsets("utf-8", "utf-16", "UTF-16BE", "utf-16le", "iso-8859-1", "koi8-r")
+
+	for _, tt := range charsetTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if tt.acceptCharset != "" {
+			req.Header.Add("Accept-Charset", tt.acceptCharset)
+		}
+		recorder := httptest.NewRecorder()
+
+		err := negotiator.Negotiate(recorder, req, "Zoë")
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.contentType, recorder.HeaderMap.Get("Content-Type"), tt.acceptCharset)
+		assert.Equal(t, tt.body, recorder.Body.String(), tt.acceptCharset)
+		assert.Equal(t, "Accept, Accept-Charset", recorder.HeaderMap.Get("Vary"))
+	}
+}
+
+func TestShouldRespondNotAcceptableForUnacceptableCharset(t *testing.T) {
+	negotiator := New(NewTXT()).WithCharsets("utf-8", "iso-8859-1")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Charset", "koi8-r, utf-8;q=0")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	var nae *NotAcceptableError
+	assert.True(t, errors.As(err, &nae))
+	assert.Equal(t, []string{"utf-8", "iso-8859-1"}, nae.Charsets)
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Supported charsets:\nutf-8\niso-8859-1\n")
+}
+
+func TestShouldNotTranscodeJSONOrCountItAgainstAcceptCharset(t *testing.T) {
+	var modeTests = []struct {
+		mode   Mode
+		accept string
+	}{
+		{LegacyMode, "application/json, application/xml;q=0.5"},
+		{RFC9110Mode, "application/json, application/xml;q=0.5"},
+		{UnifiedMode, "application/json, application/xml;q=0.5"},
+		// the XML processor is unacceptable in every charset it can send
+		{UnifiedMode, "application/xml, application/json;q=0.5"},
+	}
+
+	for _, tt := range modeTests {
+		negotiator := New(NewJSON(), NewXML()).WithMode(tt.mode).WithCharsets(CharsetLatin1)
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", tt.accept)
+		req.Header.Add("Accept-Charset", "utf-16")
+		recorder := httptest.NewRecorder()
+
+		err := negotiator.Negotiate(recorder, req, "Zoë")
+
+		assert.NoError(t, err, tt.accept)
+		assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"), tt.accept)
+		assert.Equal(t, "\"Zoë\"\n", recorder.Body.String(), tt.accept)
+	}
+}
+
+func TestShouldNotCountAcceptCharsetAgainstUndeclaredJSON(t *testing.T) {
+	var requestTests = []struct {
+		accept   string
+		fallback FallbackPolicy
+	}{
+		// matched by CanProcess rather than a declared media type
+		{"application/vnd.api+json", FallbackNotAcceptable},
+		{"image/png", FallbackFirst},
+		{"image/png", FallbackDefault},
+	}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode, UnifiedMode} {
+		for _, tt := range requestTests {
+			negotiator := New(NewJSON(), NewXML()).WithMode(mode).WithCharsets(CharsetUTF8).WithFallback(tt.fallback)
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Add("Accept", tt.accept)
+			req.Header.Add("Accept-Charset", "utf-16")
+			recorder := httptest.NewRecorder()
+
+			err := negotiator.Negotiate(recorder, req, "Zoë")
+
+			assert.NoError(t, err, tt.accept)
+			assert.Equal(t, http.StatusOK, recorder.Code, tt.accept)
+			assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"), tt.accept)
+			assert.Equal(t, "\"Zoë\"\n", recorder.Body.String(), tt.accept)
+		}
+	}
+}
+
+func TestShouldStillRefuseUnacceptableCharsetForTextFallback(t *testing.T) {
+	negotiator := New(NewJSON()).WithCharsets(CharsetUTF8).WithDefaultProcessor(NewTXT()).WithFallback(FallbackDefault)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+	req.Header.Add("Accept-Charset", "utf-16")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "Zoë")
+
+	var nae *NotAcceptableError
+	assert.True(t, errors.As(err, &nae))
+	assert.Equal(t, []string{CharsetUTF8}, nae.Charsets)
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
+
+func TestCharsetWriterShouldPassThroughTypesThatAreNotTranscoded(t *testing.T) {
+	var typeTests = []struct {
+		contentType string
+		transcoded  bool
+	}{
+		{"text/plain", true},
+		{"Text/HTML; charset=utf-8", true},
+		{"application/xml", true},
+		{"application/atom+xml", true},
+		{"image/svg+xml", true},
+		{"application/json", false},
+		{"application/problem+json", false},
+		{"application/octet-stream", false},
+		{"image/png", false},
+		{"", false},
+	}
+
+	for _, tt := range typeTests {
+		recorder := httptest.NewRecorder()
+		if tt.contentType != "" {
+			recorder.Header().Set("Content-Type", tt.contentType)
+		}
+
+		cw := newCharsetWriter(recorder, CharsetLatin1, false)
+		cw.Write([]byte("Zoë"))
+		cw.close()
+
+		assert.Equal(t, tt.transcoded, isTranscodedType(tt.contentType), tt.contentType)
+		if tt.transcoded {
+			assert.Equal(t, "Zo\xeb", recorder.Body.String(), tt.contentType)
+		} else {
+			assert.Equal(t, "Zoë", recorder.Body.String(), tt.contentType)
+			assert.Equal(t, tt.contentType, recorder.HeaderMap.Get("Content-Type"))
+		}
+	}
+}
+
+func TestCharsetWriterShouldHandleSplitSequences(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", "text/plain")
+	recorder.Header().Set("Content-Length", "7")
+
+	cw := newCharsetWriter(recorder, CharsetUTF16LE, true)
+	snowman := []byte("☃") // three bytes in UTF-8
+	cw.Write(snowman[:1])
+	cw.Write(snowman[1:])
+	cw.Write([]byte("😀\xe2"))
+	cw.close()
+
+	assert.Equal(t, "text/plain; charset=utf-16le", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "", recorder.HeaderMap.Get("Content-Length"))
+	assert.Equal(t, "\xff\xfe\x03\x26\x3d\xd8\x00\xde\xfd\xff", recorder.Body.String())
+}
+
+func TestCharsetWriterShouldWriteUTF8ByteOrderMark(t *testing.T) {
+	negotiator := New(NewCSV()).WithCharsets("utf-8").WithByteOrderMark(true)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, []string{"a", "b"})
+
+	assert.Equal(t, "\xef\xbb\xbfa,b\n", recorder.Body.String())
+}
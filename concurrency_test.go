@@ -0,0 +1,114 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests are most useful with the race detector: go test -race
+
+func TestSetContentTypeShouldNotModifySharedProcessor(t *testing.T) {
+	shared := NewJSON()
+	negotiator := New(shared)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			shared.(ContentTypeSettable).SetContentType("application/vnd.custom+json")
+		}()
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/", nil)
+			recorder := httptest.NewRecorder()
+			negotiator.Negotiate(recorder, req, "Joe")
+			assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"application/json"}, shared.(MediaTypeProcessor).MediaTypes())
+}
+
+func TestSetContentTypeShouldReturnCopies(t *testing.T) {
+	for _, processor := range []ResponseProcessor{NewJSON(), NewXML(), NewTXT(), NewCSV()} {
+		original := processor.(MediaTypeProcessor).MediaTypes()
+		custom := processor.(ContentTypeSettable).SetContentType("application/x-custom")
+
+		assert.Equal(t, original, processor.(MediaTypeProcessor).MediaTypes())
+		assert.Equal(t, []string{"application/x-custom"}, custom.(MediaTypeProcessor).MediaTypes())
+	}
+}
+
+func TestAddShouldNotShareProcessors(t *testing.T) {
+	base := New(NewJSON(), NewXML(), NewTXT()).Add(NewCSV())
+	txt, csv := NewTXT(), NewCSV()
+
+	var n1, n2 *Negotiator
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); n1 = base.AddWithQuality(0.5, txt) }()
+	go func() { defer wg.Done(); n2 = base.AddWithQuality(0.9, csv) }()
+	wg.Wait()
+
+	assert.Len(t, base.processors, 4)
+	assert.Equal(t, txt, n1.processors[4])
+	assert.Equal(t, 0.5, n1.qualities[4])
+	assert.Equal(t, csv, n2.processors[4])
+	assert.Equal(t, 0.9, n2.qualities[4])
+}
+
+func TestConstructorsShouldNotRetainCallerSlices(t *testing.T) {
+	processors := make([]ResponseProcessor, 1, 4)
+	processors[0] = NewTXT()
+
+	negotiator := NewWithJSONAndXML(processors...)
+	processors[0] = NewCSV()
+
+	assert.Equal(t, defaultTxtContentType, negotiator.processors[0].(MediaTypeProcessor).MediaTypes()[0])
+	assert.Nil(t, processors[:2][1], "spare capacity of the caller's slice was written")
+}
+
+func TestNegotiatorShouldBeSafeForConcurrentUse(t *testing.T) {
+	negotiator := NewWithJSONAndXML(NewTXT(), NewCSV()).
+		WithMode(UnifiedMode).
+		WithLanguages("en", "de").
+		WithCharsets(CharsetUTF8, CharsetLatin1).
+		WithCompression(0).
+		WithBuffering(true).
+		WithTrace(true)
+
+	var acceptTests = []string{"application/json", "application/xml", "text/plain", "text/csv", "*/*"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		accept := acceptTests[i%len(acceptTests)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", accept)
+			req.Header.Set("Accept-Language", "de")
+			req.Header.Set("Accept-Encoding", "gzip")
+			recorder := httptest.NewRecorder()
+
+			err := negotiator.Negotiate(recorder, req, "Joe")
+
+			assert.NoError(t, err, accept)
+			assert.Equal(t, http.StatusOK, recorder.Code, accept)
+			assert.Equal(t, "de", recorder.HeaderMap.Get("Content-Language"), accept)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			negotiator.WithLanguages("fr").Add(NewJSON()).WithVary("Cookie")
+		}()
+	}
+	wg.Wait()
+}
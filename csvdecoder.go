@@ -0,0 +1,103 @@
+package negotiator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+type csvDecoder struct {
+	comma rune
+}
+
+// NewCSVDecoder creates a request processor for text/csv. With no arguments, the format is
+// comma-separated; you can supply any rune to be used as an alternative separator.
+//
+// Model values should be one of the following:
+//
+// * *[][]string
+//
+// * *[]struct for some struct whose exported fields are of simple types (bool, integer,
+// float, string or encoding.TextUnmarshaler); each record fills the exported fields of
+// one struct in order, skipping unexported ones.
+func NewCSVDecoder(comma ...rune) RequestProcessor {
+	if len(comma) > 0 {
+		return &csvDecoder{comma[0]}
+	}
+	return &csvDecoder{','}
+}
+
+// Implements MediaTypeProcessor for this type.
+func (*csvDecoder) MediaTypes() []string {
+	return []string{defaultCSVContentType}
+}
+
+func (*csvDecoder) CanDecode(mediaType string) bool {
+	return mediaType == "text/csv"
+}
+
+func (p *csvDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	reader := csv.NewReader(req.Body)
+	reader.Comma = p.comma
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if v, ok := dataModel.(*[][]string); ok {
+		*v = records
+		return nil
+	}
+
+	value := reflect.ValueOf(dataModel)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Unsupported type for CSV: %T", dataModel)
+	}
+
+	slice := value.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("Unsupported type for CSV: %T", dataModel)
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(records))
+	for i, record := range records {
+		str := reflect.New(structType).Elem()
+		if err := readStructFields(str, record); err != nil {
+			return fmt.Errorf("CSV record %d: %v", i+1, err)
+		}
+		if elemType.Kind() == reflect.Ptr {
+			str = str.Addr()
+		}
+		result = reflect.Append(result, str)
+	}
+	slice.Set(result)
+	return nil
+}
+
+func readStructFields(str reflect.Value, record []string) error {
+	var fields []int
+	for i := 0; i < str.NumField(); i++ {
+		if str.Type().Field(i).PkgPath == "" {
+			fields = append(fields, i)
+		}
+	}
+
+	if len(record) > len(fields) {
+		return fmt.Errorf("%d fields, but %s has only %d exported", len(record), str.Type(), len(fields))
+	}
+
+	for k, s := range record {
+		i := fields[k]
+		if err := setScalar(str.Field(i), s); err != nil {
+			return fmt.Errorf("field %s: %v", str.Type().Field(i).Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvRow struct {
+	Name   string
+	Age    int
+	Member bool
+}
+
+func TestCSVDecoderShouldDecodeStructs(t *testing.T) {
+	var rows []csvRow
+	err := NewCSVDecoder().Decode(newBodyRequest("text/csv", "Joe,42,true\nAnn,37,false\n"), &rows)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []csvRow{{"Joe", 42, true}, {"Ann", 37, false}}, rows)
+}
+
+func TestCSVDecoderShouldDecodeStructPointersWithSeparator(t *testing.T) {
+	var rows []*csvRow
+	err := NewCSVDecoder(';').Decode(newBodyRequest("text/csv", "Joe;42\n"), &rows)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*csvRow{{Name: "Joe", Age: 42}}, rows)
+}
+
+type csvRowWithUnexported struct {
+	Name string
+	age  int
+	Age  int
+}
+
+func TestCSVDecoderShouldSkipUnexportedFields(t *testing.T) {
+	var rows []csvRowWithUnexported
+	err := NewCSVDecoder().Decode(newBodyRequest("text/csv", "Joe,42\n"), &rows)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []csvRowWithUnexported{{Name: "Joe", Age: 42}}, rows)
+
+	err = NewCSVDecoder().Decode(newBodyRequest("text/csv", "Joe,42,true\n"), &rows)
+
+	assert.EqualError(t, err, "CSV record 1: 3 fields, but negotiator.csvRowWithUnexported has only 2 exported")
+}
+
+func TestCSVDecoderShouldReportBadFields(t *testing.T) {
+	var rows []csvRow
+	err := NewCSVDecoder().Decode(newBodyRequest("text/csv", "Joe,old\n"), &rows)
+
+	assert.EqualError(t, err, `CSV record 1: field Age: strconv.ParseInt: parsing "old": invalid syntax`)
+}
+
+func TestCSVDecoderShouldRejectUnsupportedModel(t *testing.T) {
+	var n int
+	err := NewCSVDecoder().Decode(newBodyRequest("text/csv", "1\n"), &n)
+
+	assert.EqualError(t, err, "Unsupported type for CSV: *int")
+}
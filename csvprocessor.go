@@ -38,10 +38,17 @@ func NewCSV(comma ...rune) ResponseProcessor {
 	return &csvProcessor{',', defaultCSVContentType}
 }
 
-// Implements ContentTypeSettable for this type.
+// Implements ContentTypeSettable for this type. A copy of the processor is returned; the
+// original is unchanged.
 func (p *csvProcessor) SetContentType(contentType string) ResponseProcessor {
-	p.contentType = contentType
-	return p
+	p2 := *p
+	p2.contentType = contentType
+	return &p2
+}
+
+// Implements MediaTypeProcessor for this type.
+func (p *csvProcessor) MediaTypes() []string {
+	return []string{p.contentType}
 }
 
 func (*csvProcessor) CanProcess(mediaRange string) bool {
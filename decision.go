@@ -0,0 +1,236 @@
+package negotiator
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Decision describes the outcome of negotiation: which response processor would render
+// the response, and why.
+type Decision struct {
+	// Processor is the chosen response processor, or nil if none is acceptable.
+	Processor ResponseProcessor
+	// Index is the position of Processor among the Negotiator's processors, or -1 if it
+	// is the default processor given to WithDefaultProcessor, or if there is none.
+	Index int
+	// MediaType is the declared media type that was chosen, or empty if the processor
+	// was chosen by its CanProcess method, for an Ajax request, or as a fallback.
+	MediaType string
+	// MediaRange is the client's media range that selected the processor. It is "*/*"
+	// if the request has no Accept header, and the zero value for Ajax requests and
+	// fallbacks.
+	MediaRange MediaRange
+	// Language is the language chosen for the response, if the Negotiator has any (see
+	// WithLanguages).
+	Language string
+	// Charset is the charset chosen for the response, if the Negotiator has any and
+	// the chosen media type is transcoded (see WithCharsets).
+	Charset string
+	// Encoding is the content coding chosen for the response, if the Negotiator
+	// compresses responses (see WithCompression).
+	Encoding string
+	// Quality is the effective quality of the choice: the client's quality for the
+	// media range multiplied by the server quality.
+	Quality float64
+	// Score breaks down the overall score of the choice, in UnifiedMode only.
+	Score Score
+	// Vary lists the request headers that influenced the decision.
+	Vary []string
+	// Reasons explains the decision step by step, for logging and debugging. It is
+	// recorded only if tracing is on or a DecisionHook is installed, so that other
+	// decisions need not format it.
+	Reasons []string
+	// Trace records every comparison made during negotiation, if tracing is on (see
+	// WithTrace).
+	Trace []TraceEntry
+
+	// rejected records that the Accept header exceeded the parse limits under
+	// OverflowReject.
+	rejected bool
+	// explain records whether Reasons are to be recorded. Callers that pass arguments
+	// to reason test it first, so as not to box them for nothing.
+	explain bool
+}
+
+func (d *Decision) reason(format string, args ...interface{}) {
+	if d.explain {
+		d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+	}
+}
+
+// explains tests whether the Negotiator's decisions record their Reasons: only tracing
+// and decision hooks read them.
+func (n *Negotiator) explains() bool {
+	return n.trace || n.onDecision != nil
+}
+
+// Decide chooses the response processor for the request without rendering anything, so
+// that a handler can act on the choice before it has a model. It applies the fallback
+// policy as Negotiate would; if that ends in a 406 or 300 response, the Decision has no
+// Processor and a *NotAcceptableError is returned. An unacceptable charset or content
+// coding always ends in a 406 response, whatever the fallback policy, unless the
+// charset does not apply to the fallback processor (see WithCharsets). An Accept header
+// that exceeds the parse limits under OverflowReject gives a *ParseLimitError instead.
+func (n *Negotiator) Decide(req *http.Request) (Decision, error) {
+	d, charsetOK, encodingOK := n.decideHeaders(req)
+	d.Vary = append(d.Vary, n.vary...)
+
+	if d.rejected {
+		d.Processor, d.Index = nil, -1
+		return d, &ParseLimitError{Limits: n.parseLimits}
+	}
+
+	policy := n.fallbackPolicy(req)
+	fallback, fallbackIndex := n.fallbackProcessor(policy)
+	if !charsetOK && d.Processor == nil && fallback != nil && !transcodes(fallback, "") {
+		charsetOK = true // the fallback processor is sent as it is, whatever the charset
+	}
+
+	if !charsetOK || !encodingOK {
+		d.Processor, d.Index = nil, -1
+		err := n.notAcceptableError(req)
+		if !charsetOK {
+			err.Charsets = append([]string(nil), n.charsets...)
+		}
+		if !encodingOK {
+			err.Encodings = append([]string(nil), n.encodings...)
+		}
+		return d, err
+	}
+
+	if d.Processor != nil {
+		return d, nil
+	}
+
+	if fallback != nil {
+		d.Processor, d.Index = fallback, fallbackIndex
+		if !transcodes(fallback, "") {
+			d.Charset = ""
+		}
+		if d.explain {
+			d.reason("nothing acceptable; falling back to processor %d", d.Index)
+		}
+		return d, nil
+	}
+
+	d.reason("nothing acceptable")
+	return d, n.notAcceptableError(req)
+}
+
+// decide chooses a processor from the request headers alone, without any fallback.
+func (n *Negotiator) decide(req *http.Request) Decision {
+	d := Decision{Index: -1, Vary: make([]string, 0, varyCapacity), explain: n.explains()}
+
+	if ajax := n.ajaxResponder(); ajax >= 0 {
+		d.Vary = append(d.Vary, xRequestedWith)
+		if IsAjax(req) {
+			d.Processor, d.Index, d.Quality = n.processors[ajax], ajax, 1.0
+			if d.explain {
+				d.reason("Ajax request; chose Ajax responder %d", ajax)
+			}
+			return d
+		}
+	}
+
+	if len(n.processors) == 0 {
+		d.reason("no processors")
+		return d
+	}
+
+	var buf [acceptBufferSize]MediaRange
+	ranges := n.mediaRanges(req, &d, buf[:0])
+
+	var trace *[]TraceEntry
+	if n.trace {
+		trace = &d.Trace
+	}
+
+	chosen, ok := selectVariant(n.variants, ranges, n.mode, trace)
+	if !ok {
+		return d
+	}
+
+	d.Processor, d.Index = chosen.processor, chosen.index
+	d.MediaRange = ranges[chosen.rangeIndex].withParamMaps()
+	d.Quality = chosen.score
+	if chosen.offer != nil {
+		d.MediaType = chosen.offer.String()
+	}
+	switch {
+	case !d.explain:
+	case chosen.offer != nil:
+		d.reason("%s matched %s of processor %d with q=%g, qs=%g",
+			legacyValue(d.MediaRange), d.MediaType, d.Index, chosen.q, chosen.qs)
+	default:
+		d.reason("%s accepted by processor %d with q=%g, qs=%g",
+			legacyValue(d.MediaRange), d.Index, chosen.q, chosen.qs)
+	}
+	return d
+}
+
+// varyCapacity is room for every request header that negotiation itself can add to
+// Vary, so that the Vary slice is allocated only once.
+const varyCapacity = 5
+
+// acceptBufferSize is the number of media ranges that negotiation can parse, and of
+// variants that UnifiedMode can score, without allocating: enough for the Accept headers
+// of common browsers.
+const acceptBufferSize = 16
+
+// mediaRanges parses the Accept header of the request as the Negotiator's mode requires,
+// appending the media ranges to dst unless the header is absent or ignored. Their
+// parameters are kept as text (see ScanAccept).
+func (n *Negotiator) mediaRanges(req *http.Request, d *Decision, dst []MediaRange) []MediaRange {
+	d.Vary = append(d.Vary, "Accept")
+	accept := combinedHeader(req.Header, "Accept")
+	if accept == "" {
+		d.reason("no Accept header; any media type is acceptable")
+		return anyMediaRange
+	}
+
+	ranges, exceeded := n.parseAccept(dst, accept, true)
+	switch {
+	case !exceeded:
+	case n.overflow == OverflowIgnore:
+		d.reason("Accept header exceeds the parse limits; any media type is acceptable")
+		return anyMediaRange
+	case n.overflow == OverflowReject:
+		d.reason("Accept header exceeds the parse limits; rejected")
+		d.rejected = true
+		return nil
+	default:
+		if d.explain {
+			d.reason("Accept header exceeds the parse limits; truncated to %d media ranges", len(ranges))
+		}
+	}
+
+	if n.mode == LegacyMode {
+		sortByLegacyWeight(ranges)
+	}
+	return ranges
+}
+
+// SelectMediaType chooses the best of the offered media types for an Accept header, by
+// the same rules as a Negotiator in RFC9110Mode, and returns it as given. Offers may
+// carry a qs parameter giving their server quality. An empty Accept header accepts
+// anything. The empty string is returned if no offer is acceptable.
+func SelectMediaType(acceptHeader string, offers ...string) string {
+	parsed := make([]MediaRange, 0, len(offers))
+	given := make([]string, 0, len(offers))
+	for _, o := range offers {
+		if offer := parseOffer(o); offer != nil {
+			parsed = append(parsed, *offer)
+			given = append(given, o)
+		}
+	}
+
+	ranges := anyMediaRange
+	if acceptHeader != "" {
+		ranges, _ = ParseAccept(acceptHeader)
+	}
+
+	if chosen, ok := selectVariant(offerVariants(parsed), ranges, RFC9110Mode, nil); ok {
+		return given[chosen.index]
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecideShouldNotRender(t *testing.T) {
+	negotiator := New(NewXML(), NewJSON()).WithMode(RFC9110Mode).WithDecisionHook(func(*http.Request, Decision) {})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "text/html, application/*;q=0.8")
+
+	d, err := negotiator.Decide(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, negotiator.processors[0], d.Processor)
+	assert.Equal(t, 0, d.Index)
+	assert.Equal(t, "application/xml", d.MediaType)
+	assert.Equal(t, "application/*", d.MediaRange.String())
+	assert.Equal(t, 0.8, d.Quality)
+	assert.Equal(t, []string{"X-Requested-With", "Accept"}, d.Vary)
+	assert.Equal(t, []string{"application/* matched application/xml of processor 0 with q=0.8, qs=1"}, d.Reasons)
+}
+
+func TestDecideShouldAgreeWithNegotiate(t *testing.T) {
+	var acceptTests = []string{
+		"",
+		"*/*",
+		"text/csv",
+		"text/*, application/json",
+		"application/xml;q=0.5, application/json;q=0.4",
+	}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode} {
+		negotiator := New(NewJSON(), NewXML(), NewCSV()).WithMode(mode)
+
+		for _, accept := range acceptTests {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Add("Accept", accept)
+			recorder := httptest.NewRecorder()
+
+			d, _ := negotiator.Decide(req)
+			negotiator.Negotiate(recorder, req, "foo")
+
+			assert.Equal(t, d.Processor.(MediaTypeProcessor).MediaTypes()[0], recorder.HeaderMap.Get("Content-Type"), accept)
+		}
+	}
+}
+
+func TestDecideShouldReportNotAcceptable(t *testing.T) {
+	negotiator := New(NewJSON())
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+
+	d, err := negotiator.Decide(req)
+
+	assert.True(t, errors.Is(err, ErrNotAcceptable))
+	assert.Nil(t, d.Processor)
+	assert.Equal(t, -1, d.Index)
+}
+
+func TestDecideShouldApplyFallback(t *testing.T) {
+	txt := NewTXT()
+	negotiator := New(NewJSON()).WithDefaultProcessor(txt).WithFallback(FallbackDefault)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+
+	d, err := negotiator.Decide(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, txt, d.Processor)
+	assert.Equal(t, -1, d.Index)
+}
+
+func TestSelectMediaType(t *testing.T) {
+	var selectTests = []struct {
+		acceptheader string
+		offers       []string
+		expected     string
+	}{
+		{"", []string{"text/html", "application/json"}, "text/html"},
+		{"application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"text/*;q=0.5, */*;q=0.1", []string{"application/json", "text/html"}, "text/html"},
+		{"*/*", []string{"text/html;qs=0.5", "application/json"}, "application/json"},
+		{"text/html;q=0", []string{"text/html"}, ""},
+		{"image/png", []string{"text/html", "bogus"}, ""},
+		{"image/png", nil, ""},
+	}
+
+	for _, tt := range selectTests {
+		assert.Equal(t, tt.expected, SelectMediaType(tt.acceptheader, tt.offers...), tt.acceptheader)
+	}
+}
+
+func TestDecideShouldOnlyAllocateVaryWithoutReasons(t *testing.T) {
+	modes := []Mode{LegacyMode, RFC9110Mode, UnifiedMode}
+
+	for _, mode := range modes {
+		negotiator := New(NewJSON(), NewXML()).WithMode(mode)
+		for _, accept := range []string{"application/json", "*/*"} {
+			req := newAcceptRequest(accept)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				negotiator.Decide(req)
+			})
+
+			assert.Equal(t, 1.0, allocs, "%v %s", mode, accept)
+		}
+	}
+}
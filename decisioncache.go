@@ -0,0 +1,185 @@
+package negotiator
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedKeyLength bounds the memory held by each cache entry: requests whose
+// negotiated headers are longer together are negotiated without the cache.
+const maxCachedKeyLength = 1024
+
+// CacheStats reports how the decision cache of a Negotiator has fared.
+type CacheStats struct {
+	// Hits and Misses count the requests that found a decision in the cache, and those
+	// that did not.
+	Hits, Misses uint64
+	// Len is the number of decisions held, and Size the most that can be.
+	Len, Size int
+}
+
+// WithDecisionCache keeps the decisions made for the last size distinct combinations of
+// negotiated request headers (Accept, Accept-Language, Accept-Charset, Accept-Encoding
+// and whether the request is an Ajax request), so that requests repeating them are not
+// parsed and matched again. The least recently used decision is evicted when the cache
+// is full. A size of 0 or less turns caching off. Decisions are not cached while tracing
+// is on. Every Negotiator returned by a With method starts with an empty cache, so a
+// change of processors or settings never sees a stale decision. A new Negotiator is
+// returned with the same processors as the original.
+func (n *Negotiator) WithDecisionCache(size int) *Negotiator {
+	return n.With(WithDecisionCache(size))
+}
+
+// DecisionCacheStats reports the hits and misses of the decision cache since the
+// Negotiator was constructed. It is the zero value if there is no cache.
+func (n *Negotiator) DecisionCacheStats() CacheStats {
+	if n.cache == nil {
+		return CacheStats{}
+	}
+	return n.cache.stats()
+}
+
+// cachedDecision is what negotiation from the request headers alone gives, before any
+// fallback is applied.
+type cachedDecision struct {
+	d                     Decision
+	charsetOK, encodingOK bool
+}
+
+// decideHeaders chooses a processor, language, charset and content coding from the
+// request headers alone, by way of the cache if the Negotiator has one.
+func (n *Negotiator) decideHeaders(req *http.Request) (Decision, bool, bool) {
+	if n.cache == nil || n.trace {
+		return n.decideUncached(req)
+	}
+
+	key, ok := decisionKey(req)
+	if !ok {
+		return n.decideUncached(req)
+	}
+
+	if cd, ok := n.cache.get(key); ok {
+		return cd.d, cd.charsetOK, cd.encodingOK
+	}
+
+	d, charsetOK, encodingOK := n.decideUncached(req)
+	n.cache.add(key, cachedDecision{d, charsetOK, encodingOK})
+	return d, charsetOK, encodingOK
+}
+
+func (n *Negotiator) decideUncached(req *http.Request) (d Decision, charsetOK, encodingOK bool) {
+	if n.mode == UnifiedMode {
+		return n.decideUnified(req)
+	}
+
+	d = n.decide(req)
+	n.negotiateLanguage(req, &d)
+	charsetOK = n.negotiateCharset(req, &d)
+	encodingOK = n.negotiateEncoding(req, &d)
+	return d, charsetOK, encodingOK
+}
+
+// decisionKey joins the values of the request headers that negotiation depends on. It
+// returns false if they are too long to be worth caching.
+func decisionKey(req *http.Request) (string, bool) {
+	headers := [...]string{
+		combinedHeader(req.Header, "Accept"),
+		combinedHeader(req.Header, "Accept-Language"),
+		combinedHeader(req.Header, "Accept-Charset"),
+		combinedHeader(req.Header, "Accept-Encoding"),
+	}
+
+	length := 1
+	for _, h := range headers {
+		length += len(h) + 1
+	}
+	if length > maxCachedKeyLength {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.Grow(length)
+	if IsAjax(req) {
+		b.WriteByte('a')
+	} else {
+		b.WriteByte('-')
+	}
+	for _, h := range headers {
+		b.WriteByte(0) // cannot appear in a header value
+		b.WriteString(h)
+	}
+	return b.String(), true
+}
+
+// decisionCache is a least recently used cache of decisions, safe for concurrent use.
+type decisionCache struct {
+	hits, misses uint64 // accessed atomically, so first for alignment on 32-bit platforms
+	size         int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+}
+
+type cacheEntry struct {
+	key string
+	cd  cachedDecision
+}
+
+func newDecisionCache(size int) *decisionCache {
+	return &decisionCache{size: size, entries: make(map[string]*list.Element, size), order: list.New()}
+}
+
+func (c *decisionCache) get(key string) (cachedDecision, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(e)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return cachedDecision{}, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return e.Value.(*cacheEntry).cd, true
+}
+
+// add stores a decision. Its slices are clipped to their length, so that appending to
+// those of a cached copy never writes into the backing array shared by other copies.
+func (c *decisionCache) add(key string, cd cachedDecision) {
+	cd.d.Vary = cd.d.Vary[:len(cd.d.Vary):len(cd.d.Vary)]
+	cd.d.Reasons = cd.d.Reasons[:len(cd.d.Reasons):len(cd.d.Reasons)]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e) // another goroutine got here first
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, cd})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *decisionCache) stats() CacheStats {
+	c.mu.Lock()
+	length := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Len:    length,
+		Size:   c.size,
+	}
+}
@@ -0,0 +1,138 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAcceptRequest(accept string) *http.Request {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", accept)
+	return req
+}
+
+func TestDecisionCacheShouldCountHitsAndMisses(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithDecisionCache(10)
+
+	for _, accept := range []string{"application/json", "application/xml", "application/json", "application/json"} {
+		negotiator.Decide(newAcceptRequest(accept))
+	}
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Len: 2, Size: 10}, negotiator.DecisionCacheStats())
+}
+
+func TestDecisionCacheShouldAgreeWithUncachedDecisions(t *testing.T) {
+	var acceptTests = []string{
+		"",
+		"*/*",
+		"text/csv",
+		"image/png",
+		"application/xml;q=0.5, application/json;q=0.4",
+	}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode, UnifiedMode} {
+		uncached := New(NewJSON(), NewXML(), NewCSV()).WithMode(mode).WithVary("Cookie")
+		cached := uncached.WithDecisionCache(10)
+
+		for i := 0; i < 2; i++ {
+			for _, accept := range acceptTests {
+				expected, expectedErr := uncached.Decide(newAcceptRequest(accept))
+				d, err := cached.Decide(newAcceptRequest(accept))
+
+				assert.Equal(t, expected, d, accept)
+				assert.Equal(t, expectedErr, err, accept)
+			}
+		}
+	}
+}
+
+func TestDecisionCacheShouldKeyOnEveryNegotiatedHeader(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithLanguages("en", "de").WithCharsets(CharsetUTF8, CharsetLatin1).
+		WithDecisionHook(func(*http.Request, Decision) {}).WithDecisionCache(10)
+
+	req := newAcceptRequest("application/json")
+	req.Header.Set("Accept-Language", "de")
+	d, _ := negotiator.Decide(req)
+	assert.Equal(t, "de", d.Language)
+
+	req = newAcceptRequest("application/json")
+	req.Header.Set("Accept-Language", "en")
+	d, _ = negotiator.Decide(req)
+	assert.Equal(t, "en", d.Language)
+
+	req = newAcceptRequest("application/xml")
+	req.Header.Set("Accept-Charset", "iso-8859-1")
+	d, _ = negotiator.Decide(req)
+	assert.Equal(t, CharsetLatin1, d.Charset)
+
+	req = newAcceptRequest("application/json")
+	req.Header.Set(xRequestedWith, xmlHttpRequest)
+	d, _ = negotiator.Decide(req)
+	assert.Equal(t, []string{"Ajax request; chose Ajax responder 0", "chose language en", "no charset for processor 0"}, d.Reasons)
+
+	assert.Equal(t, uint64(4), negotiator.DecisionCacheStats().Misses)
+}
+
+func TestDecisionCacheShouldEvictTheLeastRecentlyUsed(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithDecisionCache(2)
+
+	negotiator.Decide(newAcceptRequest("application/json"))
+	negotiator.Decide(newAcceptRequest("application/xml"))
+	negotiator.Decide(newAcceptRequest("application/json"))
+	negotiator.Decide(newAcceptRequest("*/*")) // evicts application/xml
+	negotiator.Decide(newAcceptRequest("application/json"))
+	negotiator.Decide(newAcceptRequest("application/xml"))
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Len: 2, Size: 2}, negotiator.DecisionCacheStats())
+}
+
+func TestDecisionCacheShouldStartEmptyForEveryNegotiator(t *testing.T) {
+	original := New(NewJSON()).WithDecisionCache(10)
+	original.Decide(newAcceptRequest("application/xml"))
+
+	extended := original.Add(NewXML())
+	d, err := extended.Decide(newAcceptRequest("application/xml"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/xml", d.MediaType)
+	assert.Equal(t, CacheStats{Misses: 1, Len: 1, Size: 10}, extended.DecisionCacheStats())
+	assert.Equal(t, CacheStats{Misses: 1, Len: 1, Size: 10}, original.DecisionCacheStats())
+}
+
+func TestDecisionCacheShouldBeBypassed(t *testing.T) {
+	assert.Equal(t, CacheStats{}, New(NewJSON()).DecisionCacheStats())
+
+	traced := New(NewJSON()).WithDecisionCache(10).WithTrace(true)
+	traced.Decide(newAcceptRequest("application/json"))
+	assert.Equal(t, CacheStats{Size: 10}, traced.DecisionCacheStats())
+
+	long := New(NewJSON()).WithDecisionCache(10)
+	long.Decide(newAcceptRequest(string(make([]byte, maxCachedKeyLength))))
+	assert.Equal(t, CacheStats{Size: 10}, long.DecisionCacheStats())
+}
+
+func TestDecisionCacheShouldBeSafeForConcurrentUse(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithDecisionCache(2).WithVary("Cookie")
+	accepts := []string{"application/json", "application/xml", "*/*", "text/csv"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				recorder := httptest.NewRecorder()
+				negotiator.Negotiate(recorder, newAcceptRequest(accepts[(i+j)%len(accepts)]), "foo")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := negotiator.DecisionCacheStats()
+	assert.Equal(t, uint64(800), stats.Hits+stats.Misses)
+	assert.Equal(t, 2, stats.Len)
+}
@@ -0,0 +1,196 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is the error underlying every UnsupportedMediaTypeError, so
+// callers can test for a 415 outcome with errors.Is.
+var ErrUnsupportedMediaType = errors.New("negotiator: unsupported media type")
+
+// UnsupportedMediaTypeError is returned by Decode when none of the request processors can
+// decode the request body. The handler should respond 415 (Unsupported Media Type).
+type UnsupportedMediaTypeError struct {
+	// ContentType is the request's Content-Type header.
+	ContentType string
+	// Supported holds the media types declared by the request processors.
+	Supported []string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return ErrUnsupportedMediaType.Error() + ": content type " + strconv.Quote(e.ContentType) +
+		"; supported " + strings.Join(e.Supported, ", ")
+}
+
+// Unwrap returns ErrUnsupportedMediaType.
+func (e *UnsupportedMediaTypeError) Unwrap() error {
+	return ErrUnsupportedMediaType
+}
+
+// String returns the error message, so that the error can be rendered as plain text.
+func (e *UnsupportedMediaTypeError) String() string {
+	return e.Error()
+}
+
+// StatusCode returns 415, the status with which to respond.
+func (e *UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// defaultDecoders are used by the package-level Decode function, and by every Negotiator
+// after its own request processors unless it turns them off.
+var defaultDecoders = []RequestProcessor{
+	NewJSONDecoder(), NewXMLDecoder(), NewFormDecoder(), NewCSVDecoder(), NewTXTDecoder(),
+}
+
+// Decode reads the request body into the dataModel with the built-in request processor
+// for its Content-Type: JSON, XML, form, CSV or plain text. It returns an
+// *UnsupportedMediaTypeError if there is none, and otherwise a *DecodeError if the body
+// cannot be decoded or fails validation (see Validator).
+func Decode(req *http.Request, dataModel interface{}) error {
+	return decodeWith(defaultDecoders, DecodeOptions{}, req, dataModel)
+}
+
+// AddDecoders adds request processors, which Decode tries in order, and before the
+// built-in ones, so that they can take over a media type from them. A new Negotiator is
+// returned with the original request processors plus the extra ones.
+func (n *Negotiator) AddDecoders(requestProcessors ...RequestProcessor) *Negotiator {
+	return n.With(WithDecoders(requestProcessors...))
+}
+
+// WithBuiltInDecoders turns the built-in request processors for JSON, XML, forms, CSV
+// and plain text on or off. They are on by default, and tried after those added with
+// AddDecoders. Turn them off to decode only the media types of the added ones. A new
+// Negotiator is returned with the same processors as the original.
+func (n *Negotiator) WithBuiltInDecoders(enabled bool) *Negotiator {
+	return n.With(WithBuiltInDecoders(enabled))
+}
+
+// Decode reads the request body into the dataModel, which is normally a pointer, with
+// the first request processor that can decode the media type of the request's
+// Content-Type, applying the DecodeOptions (see WithDecodeOptions). The built-in request
+// processors are tried last (see WithBuiltInDecoders). A request without a Content-Type
+// is taken to be application/octet-stream. An *UnsupportedMediaTypeError is returned if
+// no request processor can decode the body, and a *DecodeError if the body cannot be
+// decoded or fails validation (see Validator).
+func (n *Negotiator) Decode(req *http.Request, dataModel interface{}) error {
+	return decodeWith(n.decodeChain, n.decodeOptions, req, dataModel)
+}
+
+func decodeWith(decoders []RequestProcessor, options DecodeOptions, req *http.Request, dataModel interface{}) error {
+	contentType := req.Header.Get("Content-Type")
+	mediaType := "application/octet-stream"
+	if contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return unsupportedMediaTypeError(decoders, contentType)
+		}
+		mediaType = mt
+	}
+
+	for _, decoder := range decoders {
+		if decoder.CanDecode(mediaType) {
+			return decodeBody(decoder, options, req, dataModel)
+		}
+	}
+
+	return unsupportedMediaTypeError(decoders, contentType)
+}
+
+func decodeBody(decoder RequestProcessor, options DecodeOptions, req *http.Request, dataModel interface{}) error {
+	if options.MaxBodySize > 0 {
+		if req.ContentLength > options.MaxBodySize {
+			return decodeError(&http.MaxBytesError{Limit: options.MaxBodySize})
+		}
+		req.Body = http.MaxBytesReader(nil, req.Body, options.MaxBodySize)
+	}
+
+	var err error
+	if cd, ok := decoder.(ConfigurableRequestProcessor); ok {
+		err = cd.DecodeWithOptions(req, dataModel, options)
+	} else {
+		err = decoder.Decode(req, dataModel)
+	}
+	if err != nil {
+		return decodeError(err)
+	}
+
+	if v, ok := dataModel.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return &DecodeError{Status: http.StatusUnprocessableEntity, Kind: DecodeInvalid, Message: err.Error(), Err: err}
+		}
+	}
+	return nil
+}
+
+func unsupportedMediaTypeError(decoders []RequestProcessor, contentType string) *UnsupportedMediaTypeError {
+	supported := []string{}
+	for _, decoder := range decoders {
+		if mp, ok := decoder.(MediaTypeProcessor); ok {
+			for _, mediaType := range mp.MediaTypes() {
+				if !containsFold(supported, mediaType) {
+					supported = append(supported, mediaType)
+				}
+			}
+		}
+	}
+	return &UnsupportedMediaTypeError{ContentType: contentType, Supported: supported}
+}
+
+// isJSONMediaType tests for application/json and the +json structured syntax suffix.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// setScalar parses s into a value of bool, integer, float or string kind, or into an
+// encoding.TextUnmarshaler.
+func setScalar(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if tu, ok := v.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+			return tu.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setScalar(v.Elem(), s)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type decodeUser struct {
+	Name string `json:"name" xml:"name"`
+	Age  int    `json:"age" xml:"age"`
+}
+
+func newBodyRequest(contentType, body string) *http.Request {
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestDecodeShouldChooseDecoderByContentType(t *testing.T) {
+	var decodeTests = []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", `{"name":"Joe","age":42}`},
+		{"application/json; charset=utf-8", `{"name":"Joe","age":42}`},
+		{"application/vnd.api+json", `{"name":"Joe","age":42}`},
+		{"Application/XML", `<user><name>Joe</name><age>42</age></user>`},
+		{"text/xml", `<user><name>Joe</name><age>42</age></user>`},
+	}
+
+	negotiator := NewWithJSONAndXML()
+
+	for _, tt := range decodeTests {
+		var user decodeUser
+		err := negotiator.Decode(newBodyRequest(tt.contentType, tt.body), &user)
+
+		assert.NoError(t, err, tt.contentType)
+		assert.Equal(t, decodeUser{"Joe", 42}, user, tt.contentType)
+	}
+}
+
+func TestDecodeShouldReturnUnsupportedMediaType(t *testing.T) {
+	var contentTypes = []string{"text/csv", "", "not a media type"}
+
+	negotiator := New().WithBuiltInDecoders(false).AddDecoders(NewJSONDecoder(), NewXMLDecoder())
+
+	for _, contentType := range contentTypes {
+		var user decodeUser
+		err := negotiator.Decode(newBodyRequest(contentType, "Joe,42"), &user)
+
+		var unsupported *UnsupportedMediaTypeError
+		assert.True(t, errors.Is(err, ErrUnsupportedMediaType), contentType)
+		assert.True(t, errors.As(err, &unsupported), contentType)
+		assert.Equal(t, contentType, unsupported.ContentType)
+		assert.Equal(t, []string{"application/json", "application/xml"}, unsupported.Supported)
+		assert.Equal(t, http.StatusUnsupportedMediaType, unsupported.StatusCode())
+	}
+}
+
+func TestDecodeShouldUseDecodersInOrder(t *testing.T) {
+	negotiator := New().AddDecoders(NewTXTDecoder()).AddDecoders(NewJSONDecoder())
+
+	var s string
+	err := negotiator.Decode(newBodyRequest("text/plain", "Joe Bloggs"), &s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Joe Bloggs", s)
+	assert.Len(t, negotiator.decoders, 2)
+}
+
+func TestDecodeShouldPreferAddedDecodersToBuiltInOnes(t *testing.T) {
+	negotiator := New().AddDecoders(&fakeDecoder{mediaType: "application/json"})
+
+	var s string
+	err := negotiator.Decode(newBodyRequest("application/json", `"Joe"`), &s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "fake", s)
+}
+
+func TestDecodeShouldOnlyUseAddedDecodersWithoutBuiltInOnes(t *testing.T) {
+	negotiator := New().AddDecoders(NewFormDecoder()).WithBuiltInDecoders(false)
+
+	var user decodeUser
+	err := negotiator.Decode(newBodyRequest("application/json", `{"name":"Joe"}`), &user)
+
+	var unsupported *UnsupportedMediaTypeError
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, []string{"application/x-www-form-urlencoded", "multipart/form-data"}, unsupported.Supported)
+	assert.Len(t, NewNegotiator(WithBuiltInDecoders(false)).decodeChain, 0)
+}
+
+// fakeDecoder decodes any body of its media type as the string "fake".
+type fakeDecoder struct {
+	mediaType string
+}
+
+func (d *fakeDecoder) CanDecode(mediaType string) bool {
+	return mediaType == d.mediaType
+}
+
+func (d *fakeDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	*dataModel.(*string) = "fake"
+	return nil
+}
+
+func TestPackageDecodeShouldUseBuiltInDecoders(t *testing.T) {
+	var records [][]string
+	err := Decode(newBodyRequest("text/csv", "a,b\nc,d\n"), &records)
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}}, records)
+}
+
+func TestDecodeShouldDefaultToBuiltInDecoders(t *testing.T) {
+	for _, negotiator := range []*Negotiator{New(NewJSON()), NewNegotiator(), NewWithJSONAndXML(), New().AddDecoders(NewFormDecoder())} {
+		var user decodeUser
+		err := negotiator.Decode(newBodyRequest("application/json", `{"name":"Joe","age":42}`), &user)
+		assert.NoError(t, err)
+
+		var records [][]string
+		err = negotiator.Decode(newBodyRequest("text/csv", "a,b\n"), &records)
+		assert.NoError(t, err)
+
+		var unsupported *UnsupportedMediaTypeError
+		err = negotiator.Decode(newBodyRequest("image/png", ""), &user)
+		assert.True(t, errors.As(err, &unsupported))
+		assert.Len(t, unsupported.Supported, len(unsupportedMediaTypeError(defaultDecoders, "").Supported))
+	}
+}
+
+func TestDecodeShouldReturnDecoderError(t *testing.T) {
+	var user decodeUser
+	err := NewWithJSONAndXML().Decode(newBodyRequest("application/json", "{"), &user)
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUnsupportedMediaType))
+}
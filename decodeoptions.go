@@ -0,0 +1,131 @@
+package negotiator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// DecodeOptions are the safeguards that a Negotiator applies when it decodes request
+// bodies (see WithDecodeOptions). The zero value applies none.
+type DecodeOptions struct {
+	// MaxBodySize is the largest request body, in bytes, that will be read; 0 means no
+	// limit. A larger body gives a DecodeError with status 413 (Content Too Large).
+	MaxBodySize int64
+	// DisallowUnknownFields rejects JSON objects and forms with fields that the model
+	// does not have. encoding/xml offers no way to detect unknown XML elements.
+	DisallowUnknownFields bool
+	// DisallowTrailingData rejects JSON and XML bodies with anything but white space after
+	// the value.
+	DisallowTrailingData bool
+}
+
+// ConfigurableRequestProcessor interface allows a RequestProcessor to honour the
+// DecodeOptions of the Negotiator. If a RequestProcessor also implements this interface,
+// its DecodeWithOptions method is used instead of Decode. MaxBodySize is enforced by the
+// Negotiator itself.
+type ConfigurableRequestProcessor interface {
+	DecodeWithOptions(req *http.Request, dataModel interface{}, options DecodeOptions) error
+}
+
+// Validator interface allows a model to check itself once its fields have been decoded.
+// If a model given to Decode implements it, an error from Validate is returned as a
+// DecodeError with status 422 (Unprocessable Content).
+type Validator interface {
+	Validate() error
+}
+
+// The kinds of DecodeError.
+const (
+	DecodeMalformed    = "malformed"
+	DecodeTooLarge     = "too-large"
+	DecodeUnknownField = "unknown-field"
+	DecodeTrailingData = "trailing-data"
+	DecodeInvalid      = "invalid"
+)
+
+// DecodeError is returned by Decode when the request body cannot be decoded, is too
+// large or fails validation. It is itself a model that the built-in response processors
+// can render, and Negotiate sends it with its Status:
+//
+//	if err := n.Decode(req, &user); err != nil {
+//	    n.Negotiate(w, req, err)
+//	    return
+//	}
+type DecodeError struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	// Status is the HTTP status with which to respond.
+	Status int `json:"status" xml:"status"`
+	// Kind is one of DecodeMalformed, DecodeTooLarge, DecodeUnknownField,
+	// DecodeTrailingData or DecodeInvalid.
+	Kind string `json:"kind" xml:"kind"`
+	// Field names the unknown field, for DecodeUnknownField.
+	Field string `json:"field,omitempty" xml:"field,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message" xml:"message"`
+	// Err is the underlying error, if there is one.
+	Err error `json:"-" xml:"-"`
+}
+
+func (e *DecodeError) Error() string {
+	return "negotiator: " + e.Kind + " request body: " + e.Message
+}
+
+// String returns the message, so that the error can be rendered as plain text.
+func (e *DecodeError) String() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the status with which to respond.
+func (e *DecodeError) StatusCode() int {
+	return e.Status
+}
+
+// WithDecodeOptions sets the safeguards applied by Decode. A new Negotiator is returned
+// with the same processors as the original.
+func (n *Negotiator) WithDecodeOptions(options DecodeOptions) *Negotiator {
+	return n.With(WithDecodeOptions(options))
+}
+
+// decodeError classifies an error from a request processor.
+func decodeError(err error) *DecodeError {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return de
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Kind:    DecodeTooLarge,
+			Message: fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit),
+			Err:     err,
+		}
+	}
+
+	return &DecodeError{Status: http.StatusBadRequest, Kind: DecodeMalformed, Message: err.Error(), Err: err}
+}
+
+func unknownFieldError(field string) *DecodeError {
+	return &DecodeError{
+		Status:  http.StatusBadRequest,
+		Kind:    DecodeUnknownField,
+		Field:   field,
+		Message: fmt.Sprintf("unknown field %q", field),
+	}
+}
+
+func trailingDataError() *DecodeError {
+	return &DecodeError{
+		Status:  http.StatusBadRequest,
+		Kind:    DecodeTrailingData,
+		Message: "unexpected data after the request body's value",
+	}
+}
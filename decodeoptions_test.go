@@ -0,0 +1,158 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedUser struct {
+	Name string `json:"name" xml:"name" form:"name"`
+	Age  int    `json:"age" xml:"age" form:"age"`
+}
+
+func (u *validatedUser) Validate() error {
+	if u.Age < 0 {
+		return errors.New("age must not be negative")
+	}
+	return nil
+}
+
+func TestDecodeOptionsShouldProduceDecodeErrors(t *testing.T) {
+	var decodeTests = []struct {
+		contentType string
+		body        string
+		status      int
+		kind        string
+		field       string
+	}{
+		{"application/json", `{"name":"Joe","age":42}`, 0, "", ""},
+		{"application/json", `{"name":"Joe","age":`, 400, DecodeMalformed, ""},
+		{"application/json", `{"name":"Joe","age":42,"email":"x"}`, 400, DecodeUnknownField, "email"},
+		{"application/json", `{"name":"Joe"} {"name":"Ann"}`, 400, DecodeTrailingData, ""},
+		{"application/json", `{"name":"Joe"}` + "\n", 0, "", ""},
+		{"application/json", `{"name":"` + strings.Repeat("x", 64) + `"}`, 413, DecodeTooLarge, ""},
+		{"application/json", `{"age":-1}`, 422, DecodeInvalid, ""},
+		{"application/xml", `<user><name>Joe</name></user> <!-- done -->`, 0, "", ""},
+		{"application/xml", `<user><name>Joe</name></user><user/>`, 400, DecodeTrailingData, ""},
+		{"application/xml", `<user><age>-1</age></user>`, 422, DecodeInvalid, ""},
+		{"application/x-www-form-urlencoded", "name=Joe&age=42", 0, "", ""},
+		{"application/x-www-form-urlencoded", "name=Joe&email=x", 400, DecodeUnknownField, "email"},
+	}
+
+	negotiator := NewWithJSONAndXML().AddDecoders(NewFormDecoder()).WithDecodeOptions(DecodeOptions{
+		MaxBodySize:           64,
+		DisallowUnknownFields: true,
+		DisallowTrailingData:  true,
+	})
+
+	for _, tt := range decodeTests {
+		var user validatedUser
+		err := negotiator.Decode(newBodyRequest(tt.contentType, tt.body), &user)
+
+		if tt.status == 0 {
+			assert.NoError(t, err, tt.body)
+			continue
+		}
+
+		var de *DecodeError
+		if assert.True(t, errors.As(err, &de), tt.body) {
+			assert.Equal(t, tt.status, de.StatusCode(), tt.body)
+			assert.Equal(t, tt.kind, de.Kind, tt.body)
+			assert.Equal(t, tt.field, de.Field, tt.body)
+		}
+	}
+}
+
+// TestJSONUnknownFieldErrorsShouldKeepTheirText fails if encoding/json changes the text
+// from which unknown fields are recognised, rather than letting them pass for malformed
+// bodies.
+func TestJSONUnknownFieldErrorsShouldKeepTheirText(t *testing.T) {
+	var fieldTests = []struct {
+		body  string
+		field string
+	}{
+		{`{"name":"Joe","email":"x"}`, "email"},
+		{`{"e\"mail":"x"}`, `e"mail`},
+		{`{"Émail":"x"}`, "Émail"},
+	}
+
+	for _, tt := range fieldTests {
+		decoder := json.NewDecoder(strings.NewReader(tt.body))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&validatedUser{})
+
+		assert.Equal(t, jsonUnknownFieldPrefix+strconv.Quote(tt.field), err.Error(), tt.body)
+
+		err = NewJSONDecoder().(ConfigurableRequestProcessor).DecodeWithOptions(
+			newBodyRequest("application/json", tt.body), &validatedUser{}, DecodeOptions{DisallowUnknownFields: true})
+
+		var de *DecodeError
+		assert.True(t, errors.As(err, &de), tt.body)
+		assert.Equal(t, DecodeUnknownField, de.Kind, tt.body)
+		assert.Equal(t, tt.field, de.Field, tt.body)
+	}
+}
+
+func TestDecodeOptionsShouldRejectDeclaredLengthOverLimit(t *testing.T) {
+	negotiator := NewWithJSONAndXML().WithDecodeOptions(DecodeOptions{MaxBodySize: 4})
+
+	req := newBodyRequest("application/json", `{"name":"Joe"}`)
+	req.ContentLength = 14
+
+	var user validatedUser
+	err := negotiator.Decode(req, &user)
+
+	var tooLarge *http.MaxBytesError
+	assert.True(t, errors.As(err, &tooLarge))
+	assert.EqualError(t, err, "negotiator: too-large request body: request body is larger than 4 bytes")
+}
+
+func TestDecodeShouldAllowUnknownFieldsAndTrailingDataByDefault(t *testing.T) {
+	var user validatedUser
+	err := NewWithJSONAndXML().Decode(newBodyRequest("application/json", `{"name":"Joe","email":"x"} junk`), &user)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Joe", user.Name)
+}
+
+func TestDecodeErrorShouldRenderWithItsStatus(t *testing.T) {
+	negotiator := NewWithJSONAndXML()
+
+	req := newBodyRequest("application/json", `{"name":"Joe","age":-1}`)
+	req.Header.Set("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+
+	var user validatedUser
+	err := negotiator.Decode(req, &user)
+	negotiator.Negotiate(recorder, req, err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, `{"status":422,"kind":"invalid","message":"age must not be negative"}`+"\n", recorder.Body.String())
+}
+
+func TestDecodeErrorShouldRenderAsXMLAndText(t *testing.T) {
+	de := &DecodeError{Status: 400, Kind: DecodeUnknownField, Field: "email", Message: `unknown field "email"`}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	New(NewXML(), NewTXT()).Negotiate(recorder, req, de)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `<error><status>400</status><kind>unknown-field</kind><field>email</field><message>unknown field &#34;email&#34;</message></error>`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	req.Header.Set("Accept", "text/plain")
+	New(NewXML(), NewTXT()).Negotiate(recorder, req, de)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `unknown field "email"`+"\n", recorder.Body.String())
+}
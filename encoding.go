@@ -0,0 +1,247 @@
+package negotiator
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The content codings that a Negotiator can compress responses with.
+const (
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+// DefaultCompressionMinSize is a reasonable threshold for WithCompression: below about
+// one network packet, compression rarely pays for itself.
+const DefaultCompressionMinSize = 1400
+
+// WithCompression turns on compression of responses. The content coding is chosen from
+// the Accept-Encoding header among the given encodings, which are gzip and deflate in
+// order of server preference (both, gzip first, if none are given), and identity, which
+// is chosen only if the client prefers it to all of them. Accept-Encoding is
+// added to Vary. A response is only compressed once its body reaches minSize bytes, and
+// never if its Content-Type is an already-compressed format such as an image, audio,
+// video or archive, or if it already has a Content-Encoding. If the client refuses
+// identity (with "identity;q=0" or "*;q=0") and accepts none of the encodings, the
+// response is 406 (Not Acceptable). A new Negotiator is returned with the same
+// processors as the original.
+func (n *Negotiator) WithCompression(minSize int, encodings ...string) *Negotiator {
+	return n.With(WithCompression(minSize, encodings...))
+}
+
+// negotiateEncoding chooses the content coding for the request, if the Negotiator
+// compresses responses. It returns false if neither any of the encodings nor identity
+// is acceptable.
+func (n *Negotiator) negotiateEncoding(req *http.Request, d *Decision) bool {
+	if len(n.encodings) == 0 {
+		return true
+	}
+
+	encoding, q := n.bestEncoding(req, d)
+	if q == 0 {
+		d.reason("no acceptable encoding")
+		return false
+	}
+
+	d.Encoding = encoding
+	if d.explain {
+		d.reason("chose encoding %s with q=%g", encoding, q)
+	}
+	return true
+}
+
+// bestEncoding returns the content coding with the highest quality, identity included,
+// or the empty string with quality 1 if the Negotiator does not compress responses.
+// The server's order breaks ties, identity coming last. Both modes choose the content
+// coding with it.
+func (n *Negotiator) bestEncoding(req *http.Request, d *Decision) (string, float64) {
+	if len(n.encodings) == 0 {
+		return "", 1.0
+	}
+
+	d.Vary = append(d.Vary, "Accept-Encoding")
+	acceptEncoding := combinedHeader(req.Header, "Accept-Encoding")
+
+	best, bestQ := "", 0.0
+	for _, encoding := range n.encodings {
+		if q := encodingQuality(acceptEncoding, encoding); q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	if q := encodingQuality(acceptEncoding, EncodingIdentity); q > bestQ {
+		best, bestQ = EncodingIdentity, q
+	}
+	return best, bestQ
+}
+
+// encodingQuality gives the client's quality for a content coding, following RFC 9110
+// section 12.5.3: that of the coding named, or failing that of "*". Without any
+// Accept-Encoding header, only identity is acceptable; identity is acceptable unless it
+// is explicitly refused. "x-gzip" is taken to mean gzip.
+func encodingQuality(acceptEncoding, encoding string) float64 {
+	identity := encoding == EncodingIdentity
+	if acceptEncoding == "" {
+		if identity {
+			return 1.0
+		}
+		return 0
+	}
+
+	wildcard := -1.0
+	for _, cr := range parseWeightedList(acceptEncoding) {
+		coding := strings.ToLower(cr.Value)
+		if coding == "x-gzip" {
+			coding = EncodingGzip
+		}
+
+		switch {
+		case coding == encoding:
+			return cr.Weight
+		case coding == "*" && wildcard < 0:
+			wildcard = cr.Weight
+		}
+	}
+
+	switch {
+	case wildcard >= 0:
+		return wildcard
+	case identity:
+		return 1.0
+	}
+	return 0
+}
+
+// compressedTypes lists the media types, or top-level types with a trailing "/", whose
+// content is already compressed.
+var compressedTypes = []string{
+	"image/", "audio/", "video/",
+	"application/gzip", "application/x-gzip", "application/zip", "application/zstd",
+	"application/x-bzip2", "application/x-xz", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/vnd.rar", "font/woff", "font/woff2",
+}
+
+// isCompressedType tests whether the Content-Type is an already-compressed format.
+// SVG images are text, so they are compressed.
+func isCompressedType(contentType string) bool {
+	mediaType := strings.ToLower(bareMediaType(contentType))
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+
+	for _, t := range compressedTypes {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter compresses the response body with a content coding, once the body
+// reaches a minimum size. Until then the status and body are held back.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	decided  bool
+	buf      []byte
+	cw       io.WriteCloser // nil unless compressing
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string, minSize int) *compressWriter {
+	return &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.status != 0 || c.decided {
+		return
+	}
+	c.status = code
+
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		c.decide(false)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	if !c.decided {
+		header := c.Header()
+		if header.Get("Content-Encoding") != "" || isCompressedType(header.Get("Content-Type")) {
+			if err := c.decide(false); err != nil {
+				return 0, err
+			}
+		} else {
+			c.buf = append(c.buf, p...)
+			if len(c.buf) < c.minSize {
+				return len(p), nil
+			}
+			if err := c.decide(true); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+
+	if c.cw != nil {
+		return c.cw.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// decide writes the header, compressed or not, followed by any held-back body.
+func (c *compressWriter) decide(compress bool) error {
+	c.decided = true
+
+	if compress {
+		c.Header().Set("Content-Encoding", c.encoding)
+		c.Header().Del("Content-Length")
+		if c.encoding == EncodingGzip {
+			c.cw = gzip.NewWriter(c.ResponseWriter)
+		} else {
+			// The HTTP deflate coding is the zlib format (RFC 1950) around DEFLATE data.
+			c.cw = zlib.NewWriter(c.ResponseWriter)
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	buf := c.buf
+	c.buf = nil
+	var err error
+	if c.cw != nil {
+		_, err = c.cw.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close finishes the response, writing out a body that stayed below the minimum size
+// uncompressed.
+func (c *compressWriter) close() error {
+	if !c.decided {
+		if c.status == 0 {
+			return nil // nothing was written at all
+		}
+		if err := c.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if c.cw != nil {
+		return c.cw.Close()
+	}
+	return nil
+}
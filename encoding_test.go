@@ -0,0 +1,148 @@
+package negotiator
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodingQuality(t *testing.T) {
+	var qualityTests = []struct {
+		acceptEncoding, encoding string
+		expected                 float64
+	}{
+		{"", "gzip", 0},
+		{"", "identity", 1},
+		{"gzip", "gzip", 1},
+		{"x-gzip;q=0.5", "gzip", 0.5},
+		{"GZIP;q=0.5, *;q=0.2", "deflate", 0.2},
+		{"gzip", "identity", 1},
+		{"gzip, identity;q=0", "identity", 0},
+		{"gzip, *;q=0", "identity", 0},
+		{"*;q=0, identity;q=0.1", "identity", 0.1},
+	}
+
+	for _, tt := range qualityTests {
+		assert.Equal(t, tt.expected, encodingQuality(tt.acceptEncoding, tt.encoding), tt.acceptEncoding+" "+tt.encoding)
+	}
+}
+
+func TestShouldCompressLargeResponses(t *testing.T) {
+	var compressionTests = []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"deflate, gzip;q=0.5", "deflate"},
+		{"br", ""},
+		{"", ""},
+	}
+
+	negotiator := New(NewTXT()).WithCompression(100)
+	body := strings.Repeat("negotiator ", 50)
+
+	for _, tt := range compressionTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept-Encoding", tt.acceptEncoding)
+		recorder := httptest.NewRecorder()
+
+		err := negotiator.Negotiate(recorder, req, body)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.encoding, recorder.HeaderMap.Get("Content-Encoding"), tt.acceptEncoding)
+		assert.Equal(t, "Accept, Accept-Encoding", recorder.HeaderMap.Get("Vary"))
+		assert.Equal(t, body+"\n", decompress(t, tt.encoding, recorder.Body), tt.acceptEncoding)
+	}
+}
+
+func TestShouldNotCompressSmallResponses(t *testing.T) {
+	negotiator := New(NewTXT()).WithCompression(100)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "short")
+
+	assert.Equal(t, "", recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, "short\n", recorder.Body.String())
+}
+
+func TestShouldNotCompressCompressedMediaTypes(t *testing.T) {
+	negotiator := New(NewTXT().(ContentTypeSettable).SetContentType("image/png")).WithCompression(0)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "not really a png")
+
+	assert.Equal(t, "", recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, "not really a png\n", recorder.Body.String())
+}
+
+func TestShouldNotCompressEmptyResponses(t *testing.T) {
+	negotiator := New(NewTXT()).WithCompression(0)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, nil)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "", recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, 0, recorder.Body.Len())
+}
+
+func TestShouldRespondNotAcceptableWhenIdentityIsRefused(t *testing.T) {
+	negotiator := New(NewTXT()).WithCompression(0, EncodingGzip)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Encoding", "br, identity;q=0")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	var nae *NotAcceptableError
+	assert.True(t, errors.As(err, &nae))
+	assert.Equal(t, []string{"gzip"}, nae.Encodings)
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
+
+func TestShouldCompressTranscodedResponses(t *testing.T) {
+	negotiator := New(NewTXT()).WithCharsets(CharsetLatin1).WithCompression(0)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "Zoë")
+
+	assert.Equal(t, "text/plain; charset=iso-8859-1", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "Zo\xeb\n", decompress(t, "gzip", recorder.Body))
+}
+
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	var r io.Reader = body
+	var err error
+
+	switch encoding {
+	case EncodingGzip:
+		r, err = gzip.NewReader(body)
+	case EncodingDeflate:
+		r, err = zlib.NewReader(body)
+	}
+	assert.NoError(t, err)
+
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package negotiator
+
+import (
+	"context"
+	"net/http"
+)
+
+// FallbackPolicy decides what a Negotiator does when none of its response processors
+// produces a representation that the client finds acceptable. RFC 9110 allows the server
+// either to respond 406 (Not Acceptable) or to disregard the Accept header.
+type FallbackPolicy int
+
+const (
+	// FallbackNotAcceptable responds 406 using the NotAcceptableHandler and returns a
+	// *NotAcceptableError. This is the default.
+	FallbackNotAcceptable FallbackPolicy = iota
+
+	// FallbackDefault disregards the Accept header and responds using the processor
+	// given to WithDefaultProcessor, or the first processor if none was given.
+	FallbackDefault
+
+	// FallbackFirst disregards the Accept header and responds using the first processor.
+	FallbackFirst
+
+	// FallbackMultipleChoices responds 300 (Multiple Choices) with a body listing the
+	// available media types, and a Link header for each of them. As with
+	// FallbackNotAcceptable, a *NotAcceptableError is returned, since none of the
+	// representations was sent.
+	FallbackMultipleChoices
+)
+
+type fallbackKey struct{}
+
+// WithFallback sets the policy for requests that cannot be satisfied. A new Negotiator
+// is returned with the same processors as the original.
+func (n *Negotiator) WithFallback(policy FallbackPolicy) *Negotiator {
+	return n.With(WithFallback(policy))
+}
+
+// WithDefaultProcessor sets the processor used by FallbackDefault. It need not be one of
+// the negotiated processors. A new Negotiator is returned with the same processors as the
+// original.
+func (n *Negotiator) WithDefaultProcessor(processor ResponseProcessor) *Negotiator {
+	return n.With(WithDefaultProcessor(processor))
+}
+
+// OverrideFallback returns a shallow copy of req whose context selects the given fallback
+// policy, overriding the policy of whichever Negotiator handles the request.
+func OverrideFallback(req *http.Request, policy FallbackPolicy) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), fallbackKey{}, policy))
+}
+
+// fallbackPolicy gives the policy for req.
+func (n *Negotiator) fallbackPolicy(req *http.Request) FallbackPolicy {
+	if policy, ok := req.Context().Value(fallbackKey{}).(FallbackPolicy); ok {
+		return policy
+	}
+	return n.fallback
+}
+
+// fallbackProcessor gives the processor that disregards the Accept header under the
+// policy, with its index among the processors (-1 for the default processor), or nil
+// if the policy does not use one.
+func (n *Negotiator) fallbackProcessor(policy FallbackPolicy) (ResponseProcessor, int) {
+	switch {
+	case policy == FallbackDefault && n.defaultProcessor != nil:
+		return n.defaultProcessor, -1
+	case (policy == FallbackDefault || policy == FallbackFirst) && len(n.processors) > 0:
+		return n.processors[0], 0
+	}
+	return nil, -1
+}
+
+// writeMultipleChoices responds 300 (Multiple Choices), listing the alternatives.
+func writeMultipleChoices(w http.ResponseWriter, req *http.Request, alternatives *NotAcceptableError) {
+	for _, offer := range alternatives.Offers {
+		w.Header().Add("Link", "<"+req.URL.RequestURI()+`>; rel="alternate"; type=`+quoteIfNeeded(offer))
+	}
+	writeAlternatives(w, http.StatusMultipleChoices, "Available media types:", alternatives)
+}
@@ -0,0 +1,80 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackPolicies(t *testing.T) {
+	var policyTests = []struct {
+		policy      FallbackPolicy
+		code        int
+		contentType string
+	}{
+		{FallbackNotAcceptable, http.StatusNotAcceptable, "text/plain; charset=utf-8"},
+		{FallbackDefault, http.StatusOK, "text/plain"},
+		{FallbackFirst, http.StatusOK, "application/json"},
+		{FallbackMultipleChoices, http.StatusMultipleChoices, "text/plain; charset=utf-8"},
+	}
+
+	negotiator := New(NewJSON(), NewXML()).WithDefaultProcessor(NewTXT())
+
+	for _, tt := range policyTests {
+		req, _ := http.NewRequest("GET", "/users/1?x=y", nil)
+		req.Header.Add("Accept", "image/png")
+		recorder := httptest.NewRecorder()
+
+		err := negotiator.WithFallback(tt.policy).Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, tt.code, recorder.Code)
+		assert.Equal(t, tt.contentType, recorder.HeaderMap.Get("Content-Type"))
+		assert.Equal(t, tt.policy == FallbackNotAcceptable || tt.policy == FallbackMultipleChoices, errors.Is(err, ErrNotAcceptable))
+	}
+}
+
+func TestFallbackDefaultShouldUseFirstProcessorIfNoneGiven(t *testing.T) {
+	negotiator := New(NewXML(), NewJSON()).WithFallback(FallbackDefault)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "application/xml", recorder.HeaderMap.Get("Content-Type"))
+}
+
+func TestMultipleChoicesShouldListAlternates(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithFallback(FallbackMultipleChoices)
+
+	req, _ := http.NewRequest("GET", "/users/1?x=y", nil)
+	req.Header.Add("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	var nae *NotAcceptableError
+	assert.True(t, errors.As(err, &nae))
+	assert.Equal(t, []string{"application/json", "application/xml"}, nae.Offers)
+	assert.Equal(t, []string{
+		`</users/1?x=y>; rel="alternate"; type="application/json"`,
+		`</users/1?x=y>; rel="alternate"; type="application/xml"`,
+	}, recorder.HeaderMap["Link"])
+	assert.Equal(t, "Multiple Choices\n\nAvailable media types:\napplication/json\napplication/xml\n", recorder.Body.String())
+}
+
+func TestShouldOverrideFallbackPerRequest(t *testing.T) {
+	negotiator := New(NewJSON()).WithFallback(FallbackFirst)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, OverrideFallback(req, FallbackNotAcceptable), "foo")
+
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
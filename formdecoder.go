@@ -0,0 +1,153 @@
+package negotiator
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const (
+	formContentType          = "application/x-www-form-urlencoded"
+	multipartFormContentType = "multipart/form-data"
+
+	// defaultMaxFormMemory is the part of a multipart form held in memory, as in
+	// net/http; the rest of the files are stored on disk.
+	defaultMaxFormMemory = 32 << 20
+)
+
+type formDecoder struct {
+	maxMemory int64
+}
+
+// NewFormDecoder creates a request processor for HTML forms, both URL-encoded and
+// multipart. Only the form values are decoded; the files of a multipart form remain
+// available from req.MultipartForm.
+//
+// Model values should be one of the following:
+//
+// * *url.Values or *map[string][]string
+//
+// * *map[string]string, taking the first value of each field
+//
+// * *struct for some struct in which the fields are exported and of simple types (bool,
+// integer, float, string or encoding.TextUnmarshaler), or slices of them for fields
+// with several values. A field is filled from the form field named by its "form" tag,
+// or failing that by its own name; the tag "-" skips it.
+func NewFormDecoder() RequestProcessor {
+	return &formDecoder{defaultMaxFormMemory}
+}
+
+// Implements MediaTypeProcessor for this type.
+func (*formDecoder) MediaTypes() []string {
+	return []string{formContentType, multipartFormContentType}
+}
+
+func (*formDecoder) CanDecode(mediaType string) bool {
+	return mediaType == formContentType || mediaType == multipartFormContentType
+}
+
+func (p *formDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	return p.DecodeWithOptions(req, dataModel, DecodeOptions{})
+}
+
+// Implements ConfigurableRequestProcessor for this type. DisallowUnknownFields applies
+// to struct models only.
+func (p *formDecoder) DecodeWithOptions(req *http.Request, dataModel interface{}, options DecodeOptions) error {
+	var err error
+	if strings.HasPrefix(strings.ToLower(req.Header.Get("Content-Type")), multipartFormContentType) {
+		err = req.ParseMultipartForm(p.maxMemory)
+	} else {
+		err = req.ParseForm()
+	}
+	if err != nil {
+		return err
+	}
+
+	form := req.PostForm
+	switch v := dataModel.(type) {
+	case *url.Values:
+		*v = form
+		return nil
+	case *map[string][]string:
+		*v = form
+		return nil
+	case *map[string]string:
+		*v = make(map[string]string, len(form))
+		for name := range form {
+			(*v)[name] = form.Get(name)
+		}
+		return nil
+	}
+
+	value := reflect.ValueOf(dataModel)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unsupported type for form: %T", dataModel)
+	}
+
+	if options.DisallowUnknownFields {
+		fields := formFieldNames(value.Elem().Type())
+		for name := range form {
+			if !fields[name] {
+				return unknownFieldError(name)
+			}
+		}
+	}
+	return readFormFields(value.Elem(), form)
+}
+
+// formFieldNames returns the form field names of a struct type.
+func formFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := formFieldName(t.Field(i)); ok {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// formFieldName returns the form field name of a struct field, or false if the field is
+// skipped.
+func formFieldName(field reflect.StructField) (string, bool) {
+	name := field.Tag.Get("form")
+	if name == "-" || field.PkgPath != "" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func readFormFields(str reflect.Value, form url.Values) error {
+	for i := 0; i < str.NumField(); i++ {
+		name, ok := formFieldName(str.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		values, ok := form[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		fv := str.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, s := range values {
+				if err := setScalar(slice.Index(j), s); err != nil {
+					return fmt.Errorf("form field %s: %v", name, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if err := setScalar(fv, values[0]); err != nil {
+			return fmt.Errorf("form field %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package negotiator
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signup struct {
+	Name     string   `form:"name"`
+	Age      int      `form:"age"`
+	Tags     []string `form:"tag"`
+	Password string   `form:"-"`
+	Email    string
+}
+
+func TestFormDecoderShouldDecodeStruct(t *testing.T) {
+	req := newBodyRequest(formContentType, "name=Joe&age=42&tag=a&tag=b&Password=x&Email=joe%40example.com")
+
+	var s signup
+	err := NewFormDecoder().Decode(req, &s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, signup{Name: "Joe", Age: 42, Tags: []string{"a", "b"}, Email: "joe@example.com"}, s)
+}
+
+func TestFormDecoderShouldDecodeMaps(t *testing.T) {
+	var values url.Values
+	err := NewFormDecoder().Decode(newBodyRequest(formContentType, "a=1&a=2&b=3"), &values)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"a": {"1", "2"}, "b": {"3"}}, values)
+
+	var m map[string]string
+	err = NewFormDecoder().Decode(newBodyRequest(formContentType, "a=1&a=2&b=3"), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, m)
+}
+
+func TestFormDecoderShouldDecodeMultipartForm(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "Joe")
+	mw.WriteField("age", "42")
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var s signup
+	err := NewWithJSONAndXML().AddDecoders(NewFormDecoder()).Decode(req, &s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, signup{Name: "Joe", Age: 42}, s)
+}
+
+func TestFormDecoderShouldReportBadFields(t *testing.T) {
+	var s signup
+	err := NewFormDecoder().Decode(newBodyRequest(formContentType, "age=old"), &s)
+
+	assert.EqualError(t, err, `form field age: strconv.ParseInt: parsing "old": invalid syntax`)
+}
@@ -0,0 +1,70 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// jsonUnknownFieldPrefix begins the text of the errors with which encoding/json reports
+// unknown fields, which it gives no type of their own. A test pins it to the toolchain.
+const jsonUnknownFieldPrefix = "json: unknown field "
+
+type jsonDecoder struct{}
+
+// NewJSONDecoder creates a request processor for JSON, including media types with the
+// +json suffix.
+func NewJSONDecoder() RequestProcessor {
+	return &jsonDecoder{}
+}
+
+// Implements MediaTypeProcessor for this type.
+func (*jsonDecoder) MediaTypes() []string {
+	return []string{defaultJSONContentType}
+}
+
+func (*jsonDecoder) CanDecode(mediaType string) bool {
+	return isJSONMediaType(mediaType)
+}
+
+func (p *jsonDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	return p.DecodeWithOptions(req, dataModel, DecodeOptions{})
+}
+
+// Implements ConfigurableRequestProcessor for this type.
+func (*jsonDecoder) DecodeWithOptions(req *http.Request, dataModel interface{}, options DecodeOptions) error {
+	decoder := json.NewDecoder(req.Body)
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(dataModel); err != nil {
+		if msg := err.Error(); strings.HasPrefix(msg, jsonUnknownFieldPrefix) {
+			if field, uerr := strconv.Unquote(msg[len(jsonUnknownFieldPrefix):]); uerr == nil {
+				return unknownFieldError(field)
+			}
+		}
+		return err
+	}
+
+	if options.DisallowTrailingData {
+		return checkEOF(decoder.Token())
+	}
+	return nil
+}
+
+// checkEOF reports trailing data unless the token reader is at the end of its input. A
+// body that is too large is reported as such.
+func checkEOF(_ interface{}, err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == io.EOF:
+		return nil
+	case errors.As(err, &tooLarge):
+		return err
+	}
+	return trailingDataError()
+}
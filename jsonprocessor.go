@@ -29,10 +29,17 @@ func NewJSONIndent2Spaces() ResponseProcessor {
 	return NewJSONIndent("", "  ")
 }
 
-// Implements ContentTypeSettable for this type.
+// Implements ContentTypeSettable for this type. A copy of the processor is returned; the
+// original is unchanged.
 func (p *jsonProcessor) SetContentType(contentType string) ResponseProcessor {
-	p.contentType = contentType
-	return p
+	p2 := *p
+	p2.contentType = contentType
+	return &p2
+}
+
+// Implements MediaTypeProcessor for this type.
+func (p *jsonProcessor) MediaTypes() []string {
+	return []string{p.contentType}
 }
 
 // Implements AjaxResponseProcessor for this type.
@@ -0,0 +1,179 @@
+package negotiator
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type languageKey struct{}
+
+// WithLanguages sets the language tags in which responses are available, such as "en-GB"
+// or "de". The first is the default. The Negotiator then chooses a language for every
+// response by RFC 4647 lookup against the Accept-Language header, sets Content-Language,
+// adds Accept-Language to Vary, and passes the language to the response processor in the
+// request context (see NegotiatedLanguage). A new Negotiator is returned with the same
+// processors as the original.
+func (n *Negotiator) WithLanguages(tags ...string) *Negotiator {
+	return n.With(WithLanguages(tags...))
+}
+
+// NegotiatedLanguage returns the language chosen for the response, or the empty string
+// if there was none. Response processors can call it with the request they are given.
+func NegotiatedLanguage(req *http.Request) string {
+	language, _ := req.Context().Value(languageKey{}).(string)
+	return language
+}
+
+// withLanguage returns a shallow copy of req carrying the chosen language.
+func withLanguage(req *http.Request, language string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), languageKey{}, language))
+}
+
+// LookupLanguage implements the lookup scheme of RFC 4647 section 3.4. Each language
+// range in the Accept-Language header is tried in order of quality; a range that matches
+// none of the tags is progressively truncated, so that "de-CH-1996" is tried as "de-CH"
+// and then "de". Ranges with q=0 and the "*" range are skipped, and so are the tags that
+// a range with q=0 refuses, however they are reached: "de-CH, de;q=0" does not match
+// "de". A range with q=0 does not refuse a tag that a longer range matches, so the same
+// header still matches "de-CH". The first tag to match is returned, ignoring case, or
+// defaultTag if none does.
+func LookupLanguage(acceptLanguage, defaultTag string, tags ...string) string {
+	ranges := parseWeightedList(acceptLanguage)
+	for _, lr := range ranges {
+		if lr.Weight == 0 || lr.Value == "*" {
+			continue
+		}
+
+		for r := lr.Value; r != ""; r = truncateLanguageRange(r) {
+			for _, tag := range tags {
+				if strings.EqualFold(tag, r) && !refusesLanguage(ranges, tag) {
+					return tag
+				}
+			}
+		}
+	}
+
+	return defaultTag
+}
+
+// refusesLanguage tests whether the longest language range, other than "*", that matches
+// the tag by basic filtering has q=0. A range with q=0 wins a tie, so "de, de;q=0"
+// refuses "de".
+func refusesLanguage(ranges []weightedValue, tag string) bool {
+	refused, longest := false, -1
+	for _, lr := range ranges {
+		if lr.Value == "*" || !languageRangeMatches(lr.Value, tag) {
+			continue
+		}
+		if len(lr.Value) > longest || len(lr.Value) == longest && lr.Weight == 0 {
+			refused, longest = lr.Weight == 0, len(lr.Value)
+		}
+	}
+	return refused
+}
+
+// truncateLanguageRange removes the last subtag from a language range, together with
+// any single-character subtag that would then be left at the end.
+func truncateLanguageRange(r string) string {
+	i := strings.LastIndexByte(r, '-')
+	if i < 0 {
+		return ""
+	}
+
+	r = r[:i]
+	if i >= 2 && r[i-2] == '-' {
+		r = r[:i-2]
+	}
+	return r
+}
+
+// FilterLanguages implements the basic filtering scheme of RFC 4647 section 3.3.1. It
+// returns the tags matched by any language range in the Accept-Language header with a
+// non-zero quality, ordered by that quality. A range matches a tag if it equals the tag
+// or is a prefix of it followed by "-", ignoring case; "*" matches every tag.
+func FilterLanguages(acceptLanguage string, tags ...string) []string {
+	var filtered []string
+
+	for _, lr := range parseWeightedList(acceptLanguage) {
+		if lr.Weight == 0 {
+			continue
+		}
+
+		for _, tag := range tags {
+			if languageRangeMatches(lr.Value, tag) && !containsFold(filtered, tag) {
+				filtered = append(filtered, tag)
+			}
+		}
+	}
+
+	return filtered
+}
+
+func languageRangeMatches(r, tag string) bool {
+	if r == "*" || strings.EqualFold(r, tag) {
+		return true
+	}
+	return len(tag) > len(r) && tag[len(r)] == '-' && strings.EqualFold(tag[:len(r)], r)
+}
+
+// negotiateLanguage chooses the language for the request, if the Negotiator has any.
+func (n *Negotiator) negotiateLanguage(req *http.Request, d *Decision) {
+	if len(n.languages) == 0 {
+		return
+	}
+
+	d.Vary = append(d.Vary, "Accept-Language")
+	d.Language = LookupLanguage(combinedHeader(req.Header, "Accept-Language"), n.languages[0], n.languages...)
+	if d.explain {
+		d.reason("chose language %s", d.Language)
+	}
+}
+
+// unlistedLanguageQuality is the quality, in UnifiedMode, of a language that the
+// Accept-Language header neither names nor refuses. As in Apache, it is small but not
+// zero, so that such a representation loses to any acceptable one but is still better
+// than none.
+const unlistedLanguageQuality = 0.001
+
+// languageQuality gives the client's quality for a language tag in UnifiedMode: that of
+// the longest language range that matches the tag by basic filtering, or failing that of
+// the first range that matches it by lookup once truncated, so that "de-CH" accepts "de"
+// unless a range with q=0 refuses it, or failing that of "*". Without an Accept-Language
+// header every language scores 1.
+func languageQuality(acceptLanguage, tag string) float64 {
+	if acceptLanguage == "" {
+		return 1.0
+	}
+
+	ranges := parseWeightedList(acceptLanguage)
+	refused := refusesLanguage(ranges, tag)
+	q, longest := unlistedLanguageQuality, -1
+	lookup := -1.0
+	for _, lr := range ranges {
+		if languageRangeMatches(lr.Value, tag) {
+			length := len(lr.Value)
+			if lr.Value == "*" {
+				length = 0
+			}
+			if length > longest {
+				q, longest = lr.Weight, length
+			}
+			continue
+		}
+
+		if lookup < 0 && lr.Weight > 0 && !refused {
+			for r := truncateLanguageRange(lr.Value); r != ""; r = truncateLanguageRange(r) {
+				if strings.EqualFold(r, tag) {
+					lookup = lr.Weight
+					break
+				}
+			}
+		}
+	}
+
+	if longest <= 0 && lookup >= 0 {
+		return lookup
+	}
+	return q
+}
@@ -0,0 +1,103 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupLanguage(t *testing.T) {
+	var lookupTests = []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "en"},
+		{"de-CH", "de"},
+		{"de-CH-1996", "de"},
+		{"zh-Hant-CN-x-private1", "zh-Hant"},
+		{"fr-CA, de;q=0.5", "fr-CA"},
+		{"FR-ca", "fr-CA"},
+		{"de;q=0.5, fr", "de"},
+		{"es, *", "en"},
+		{"de;q=0, fr-CA;q=0", "en"},
+		{"de-CH, de;q=0", "en"},
+		{"de-CH-1996, DE;q=0, fr-CA;q=0.5", "fr-CA"},
+		{"zh-Hant-CN, zh-Hant-TW;q=0", "zh-Hant"},
+	}
+
+	for _, tt := range lookupTests {
+		assert.Equal(t, tt.expected, LookupLanguage(tt.acceptLanguage, "en", "en", "de", "fr-CA", "zh-Hant"), tt.acceptLanguage)
+	}
+}
+
+func TestLookupLanguageShouldNotRefuseTagsMatchedByLongerRanges(t *testing.T) {
+	var lookupTests = []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"de-CH, de;q=0", "de-CH"},
+		{"de;q=0, de-CH", "de-CH"},
+		{"de-CH;q=0, de-CH", "de"},
+		{"de, de-CH;q=0", "de"},
+	}
+
+	for _, tt := range lookupTests {
+		assert.Equal(t, tt.expected, LookupLanguage(tt.acceptLanguage, "en", "de-CH", "de", "en"), tt.acceptLanguage)
+	}
+}
+
+func TestFilterLanguages(t *testing.T) {
+	var filterTests = []struct {
+		acceptLanguage string
+		expected       []string
+	}{
+		{"de", []string{"de", "de-CH", "de-DE"}},
+		{"de-ch", []string{"de-CH"}},
+		{"fr, de-DE;q=0.5", []string{"de-DE"}},
+		{"de-DE;q=0.5, en", []string{"en-GB", "de-DE"}},
+		{"*;q=0.1, de-CH", []string{"de-CH", "en-GB", "de", "de-DE"}},
+		{"de;q=0", nil},
+	}
+
+	for _, tt := range filterTests {
+		assert.Equal(t, tt.expected, FilterLanguages(tt.acceptLanguage, "en-GB", "de", "de-CH", "de-DE"), tt.acceptLanguage)
+	}
+}
+
+func TestShouldNegotiateLanguage(t *testing.T) {
+	language := &fakeLanguageProcessor{}
+	negotiator := New(language).WithLanguages("en", "de")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Language", "de-AT, en;q=0.5")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "de", recorder.Body.String())
+	assert.Equal(t, "de", recorder.HeaderMap.Get("Content-Language"))
+	assert.Equal(t, "Accept, Accept-Language", recorder.HeaderMap.Get("Vary"))
+}
+
+func TestShouldNotNegotiateLanguageWithoutLanguages(t *testing.T) {
+	negotiator := New(&fakeLanguageProcessor{})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Language", "de-AT, en;q=0.5")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "", recorder.Body.String())
+	assert.Equal(t, "", recorder.HeaderMap.Get("Content-Language"))
+	assert.Equal(t, "Accept", recorder.HeaderMap.Get("Vary"))
+}
+
+type fakeLanguageProcessor struct{ fakeProcessor }
+
+func (*fakeLanguageProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	w.Write([]byte(NegotiatedLanguage(req)))
+	return nil
+}
@@ -0,0 +1,117 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ParseLimits bound the work done parsing an Accept header, so that a client cannot make
+// the server spend time and memory on an absurd one. A limit of 0 means no limit.
+type ParseLimits struct {
+	// MaxBytes is the longest header that is parsed.
+	MaxBytes int
+	// MaxRanges is the most media ranges, valid or not, that are parsed.
+	MaxRanges int
+	// MaxParams is the most parameters of a media range, counting q and any accept
+	// extensions, that are parsed.
+	MaxParams int
+}
+
+// DefaultParseLimits are the limits of a new Negotiator. They are far beyond anything
+// that real clients send.
+var DefaultParseLimits = ParseLimits{MaxBytes: 4096, MaxRanges: 64, MaxParams: 16}
+
+// cut shortens the header to MaxBytes, after the last whole element that fits, and
+// reports whether it had to. Commas inside quoted strings do not end an element. No
+// more than MaxBytes+1 bytes are looked at.
+func (l ParseLimits) cut(header string) (string, bool) {
+	if l.MaxBytes <= 0 || len(header) <= l.MaxBytes {
+		return header, false
+	}
+
+	end := 0
+	elements := listScanner{s: header[:l.MaxBytes+1], sep: ','}
+	for sp, ok := elements.next(); ok && !elements.done; sp, ok = elements.next() {
+		end = sp.end // the element ends at a comma within the limit
+	}
+	return header[:end], true
+}
+
+// OverflowPolicy decides what a Negotiator does with an Accept header that exceeds its
+// parse limits.
+type OverflowPolicy int
+
+const (
+	// OverflowTruncate negotiates with what was parsed within the limits. It is the
+	// default.
+	OverflowTruncate OverflowPolicy = iota
+	// OverflowIgnore disregards the Accept header, as if the request had none.
+	OverflowIgnore
+	// OverflowReject responds 400 (Bad Request) with problem details, and Negotiate
+	// returns a *ParseLimitError.
+	OverflowReject
+)
+
+// ErrParseLimitExceeded is the error underlying every ParseLimitError, so callers can
+// test for it with errors.Is.
+var ErrParseLimitExceeded = errors.New("negotiator: Accept header exceeds the parse limits")
+
+// ParseLimitError is returned by Negotiate and Decide when the Accept header exceeds the
+// parse limits and the Negotiator rejects such headers (see OverflowReject).
+type ParseLimitError struct {
+	// Limits are those that the header exceeded.
+	Limits ParseLimits
+}
+
+func (e *ParseLimitError) Error() string {
+	var limits []string
+	if e.Limits.MaxBytes > 0 {
+		limits = append(limits, fmt.Sprintf("%d bytes", e.Limits.MaxBytes))
+	}
+	if e.Limits.MaxRanges > 0 {
+		limits = append(limits, fmt.Sprintf("%d media ranges", e.Limits.MaxRanges))
+	}
+	if e.Limits.MaxParams > 0 {
+		limits = append(limits, fmt.Sprintf("%d parameters per media range", e.Limits.MaxParams))
+	}
+
+	if len(limits) == 0 {
+		return ErrParseLimitExceeded.Error()
+	}
+	return ErrParseLimitExceeded.Error() + " of " + strings.Join(limits, ", ")
+}
+
+// Unwrap returns ErrParseLimitExceeded.
+func (e *ParseLimitError) Unwrap() error {
+	return ErrParseLimitExceeded
+}
+
+// StatusCode returns 400 (Bad Request).
+func (e *ParseLimitError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// ProblemDetails describes the error as problem details. The limits themselves are not
+// revealed.
+func (e *ParseLimitError) ProblemDetails() ProblemDetails {
+	return ProblemDetails{Status: http.StatusBadRequest, Detail: "The Accept header is too large."}
+}
+
+// WithParseLimits sets the limits on parsing Accept headers, which are
+// DefaultParseLimits for a new Negotiator, and what to do with a header that exceeds
+// them. A zero ParseLimits turns the limits off. A new Negotiator is returned with the
+// same processors as the original.
+func (n *Negotiator) WithParseLimits(limits ParseLimits, policy OverflowPolicy) *Negotiator {
+	return n.With(WithParseLimits(limits, policy))
+}
+
+// parseAccept parses the Accept header within the Negotiator's limits, appending the
+// media ranges to dst, and reports whether the limits were exceeded. If lazy is set,
+// parameters are kept as text (see ScanAccept).
+func (n *Negotiator) parseAccept(dst []MediaRange, accept string, lazy bool) ([]MediaRange, bool) {
+	header, cut := n.parseLimits.cut(accept)
+	ranges, exceeded := appendMediaRanges(dst, header, n.parseLimits, lazy, nil)
+	return ranges, cut || exceeded
+}
@@ -0,0 +1,122 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptWithLimits(t *testing.T) {
+	var limitTests = []struct {
+		header   string
+		limits   ParseLimits
+		expected []string
+		exceeded bool
+	}{
+		{"text/html, application/json", ParseLimits{}, []string{"text/html", "application/json"}, false},
+		{"text/html, application/json", ParseLimits{MaxBytes: 27, MaxRanges: 2, MaxParams: 1}, []string{"text/html", "application/json"}, false},
+		{"text/html, application/json", ParseLimits{MaxBytes: 20}, []string{"text/html"}, true},
+		{"text/html, application/json", ParseLimits{MaxBytes: 9}, []string{"text/html"}, true},
+		{"text/html, application/json", ParseLimits{MaxBytes: 5}, nil, true},
+		{"text/html, , application/json, */*", ParseLimits{MaxRanges: 2}, []string{"text/html", "application/json"}, true},
+		{"bogus, text/html, application/json", ParseLimits{MaxRanges: 2}, []string{"text/html"}, true},
+		{"text/html;level=1;a=b;q=0.5", ParseLimits{MaxParams: 2}, nil, true},
+		{"text/html;a=1;b=2;q=0, application/json;q=0.5", ParseLimits{MaxParams: 2}, []string{"application/json"}, true},
+		{"text/html;level=1;q=0.5", ParseLimits{MaxParams: 2}, []string{"text/html;level=1"}, false},
+		{`text/html;a="x,y", application/json`, ParseLimits{MaxBytes: 20}, []string{`text/html;a="x,y"`}, true},
+		{`text/html;a="x,y", application/json`, ParseLimits{MaxBytes: 14}, nil, true},
+	}
+
+	for _, tt := range limitTests {
+		ranges, _, exceeded := ParseAcceptWithLimits(tt.header, tt.limits)
+
+		var got []string
+		for _, mr := range ranges {
+			got = append(got, mr.String())
+		}
+		assert.Equal(t, tt.expected, got, "%s %+v", tt.header, tt.limits)
+		assert.Equal(t, tt.exceeded, exceeded, "%s %+v", tt.header, tt.limits)
+	}
+}
+
+// tooManyRanges has more media ranges than DefaultParseLimits allow, with JSON last.
+var tooManyRanges = strings.Repeat("image/png, ", DefaultParseLimits.MaxRanges) + "application/json"
+
+func TestNegotiatorShouldApplyDefaultParseLimits(t *testing.T) {
+	negotiator := New(NewJSON()).WithDecisionHook(func(*http.Request, Decision) {})
+
+	d, err := negotiator.Decide(newAcceptRequest(tooManyRanges))
+
+	assert.True(t, errors.Is(err, ErrNotAcceptable))
+	assert.Contains(t, d.Reasons, "Accept header exceeds the parse limits; truncated to 64 media ranges")
+}
+
+func TestNegotiatorShouldAllowUnlimitedParsing(t *testing.T) {
+	negotiator := New(NewJSON()).WithParseLimits(ParseLimits{}, OverflowReject)
+
+	d, err := negotiator.Decide(newAcceptRequest(tooManyRanges))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", d.MediaType)
+}
+
+func TestNegotiatorShouldIgnoreOverlongAcceptHeaders(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).WithParseLimits(ParseLimits{MaxBytes: 16}, OverflowIgnore)
+
+	d, err := negotiator.Decide(newAcceptRequest("application/xml;q=0.1, text/html"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", d.MediaType)
+	assert.Equal(t, "*/*", d.MediaRange.String())
+}
+
+func TestNegotiatorShouldRejectOverlongAcceptHeaders(t *testing.T) {
+	var hooked error
+	negotiator := New(NewJSON()).
+		WithParseLimits(ParseLimits{MaxParams: 2}, OverflowReject).
+		WithErrorHook(func(req *http.Request, err error) { hooked = err })
+
+	req := newAcceptRequest("application/json;a=1;b=2;c=3")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	assert.True(t, errors.Is(err, ErrParseLimitExceeded))
+	assert.Equal(t, err, hooked)
+	assert.Equal(t, &ParseLimitError{Limits: ParseLimits{MaxParams: 2}}, err)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, `{"title":"Bad Request","status":400,"detail":"The Accept header is too large."}`+"\n", recorder.Body.String())
+}
+
+func TestParseLimitErrorShouldOnlyDescribeLimitsThatAreSet(t *testing.T) {
+	var errorTests = []struct {
+		limits   ParseLimits
+		expected string
+	}{
+		{ParseLimits{MaxBytes: 10, MaxRanges: 2, MaxParams: 3}, ErrParseLimitExceeded.Error() + " of 10 bytes, 2 media ranges, 3 parameters per media range"},
+		{ParseLimits{MaxRanges: 2}, ErrParseLimitExceeded.Error() + " of 2 media ranges"},
+		{ParseLimits{}, ErrParseLimitExceeded.Error()},
+	}
+
+	for _, tt := range errorTests {
+		assert.EqualError(t, &ParseLimitError{Limits: tt.limits}, tt.expected)
+	}
+}
+
+func TestDecideShouldReportParseLimitError(t *testing.T) {
+	negotiator := New(NewJSON()).WithParseLimits(ParseLimits{MaxRanges: 1}, OverflowReject)
+
+	d, err := negotiator.Decide(newAcceptRequest("application/json, application/xml"))
+
+	var le *ParseLimitError
+	assert.True(t, errors.As(err, &le))
+	assert.Equal(t, http.StatusBadRequest, le.StatusCode())
+	assert.Nil(t, d.Processor)
+	assert.Equal(t, -1, d.Index)
+	assert.Equal(t, []string{"X-Requested-With", "Accept"}, d.Vary)
+}
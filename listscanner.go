@@ -0,0 +1,52 @@
+package negotiator
+
+// span locates a piece of text by its byte offsets, from start up to end.
+type span struct {
+	start, end int
+}
+
+// trim moves the span inward past any spaces and tabs at either end of it in s.
+func (sp span) trim(s string) span {
+	for sp.start < sp.end && (s[sp.start] == ' ' || s[sp.start] == '\t') {
+		sp.start++
+	}
+	for sp.end > sp.start && (s[sp.end-1] == ' ' || s[sp.end-1] == '\t') {
+		sp.end--
+	}
+	return sp
+}
+
+// listScanner splits s at each sep that is not inside a quoted string, such as the
+// elements of a header list at "," or the parameters of an element at ";". It works on
+// byte offsets and does not allocate. As with strings.Split, there is always at least
+// one part, possibly empty.
+type listScanner struct {
+	s    string
+	sep  byte
+	pos  int
+	done bool
+}
+
+// next returns the span of the next part, or false if there are no more.
+func (ls *listScanner) next() (span, bool) {
+	if ls.done {
+		return span{}, false
+	}
+
+	start := ls.pos
+	inQuotes := false
+	for i := start; i < len(ls.s); i++ {
+		switch c := ls.s[i]; {
+		case inQuotes && c == '\\':
+			i++ // skip the quoted-pair
+		case c == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && c == ls.sep:
+			ls.pos = i + 1
+			return span{start, i}, true
+		}
+	}
+
+	ls.done = true
+	return span{start, len(ls.s)}, true
+}
@@ -0,0 +1,173 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanAll(s string, sep byte) []string {
+	var parts []string
+	ls := listScanner{s: s, sep: sep}
+	for sp, ok := ls.next(); ok; sp, ok = ls.next() {
+		parts = append(parts, s[sp.start:sp.end])
+	}
+	return parts
+}
+
+func TestListScanner(t *testing.T) {
+	var scanTests = []struct {
+		s        string
+		expected []string
+	}{
+		{"", []string{""}},
+		{"a", []string{"a"}},
+		{"a,b", []string{"a", "b"}},
+		{"a,", []string{"a", ""}},
+		{",,", []string{"", "", ""}},
+		{`a;x="1,2",b`, []string{`a;x="1,2"`, "b"}},
+		{`a;x="q\",",b`, []string{`a;x="q\","`, "b"}},
+		{`a;x="unterminated,b`, []string{`a;x="unterminated,b`}},
+	}
+
+	for _, tt := range scanTests {
+		assert.Equal(t, tt.expected, scanAll(tt.s, ','), tt.s)
+	}
+}
+
+func TestSpanTrim(t *testing.T) {
+	s := " \ta b\t "
+	sp := span{0, len(s)}.trim(s)
+	assert.Equal(t, "a b", s[sp.start:sp.end])
+
+	sp = span{0, 3}.trim("   ")
+	assert.Equal(t, sp.start, sp.end)
+}
+
+func TestListScannerShouldNotAllocate(t *testing.T) {
+	header := `text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8;ext="a,b"`
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ls := listScanner{s: header, sep: ','}
+		for sp, ok := ls.next(); ok; sp, ok = ls.next() {
+			sp = sp.trim(header)
+			params := listScanner{s: header[sp.start:sp.end], sep: ';'}
+			for _, ok := params.next(); ok; _, ok = params.next() {
+			}
+		}
+	})
+
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestParseAcceptShouldOnlyAllocateTheResultAndParamMaps(t *testing.T) {
+	var allocTests = []struct {
+		header string
+		allocs float64
+	}{
+		{"*/*", 1},
+		{"application/json", 1},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", 1},
+		// the result, then a map, header and buckets, for the v parameter
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7", 3},
+		// and another for the extensions that follow q
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7;ext=1", 5},
+	}
+
+	for _, tt := range allocTests {
+		allocs := testing.AllocsPerRun(100, func() {
+			ParseAccept(tt.header)
+		})
+
+		assert.Equal(t, tt.allocs, allocs, tt.header)
+	}
+}
+
+func TestScanAcceptShouldNotAllocate(t *testing.T) {
+	for _, bm := range benchmarkAccepts {
+		allocs := testing.AllocsPerRun(100, func() {
+			ScanAccept(bm.accept, DefaultParseLimits, func(mr MediaRange) bool {
+				mr.Param("v")
+				return true
+			})
+		})
+
+		assert.Equal(t, 0.0, allocs, bm.name)
+	}
+}
+
+func TestScanAcceptShouldAgreeWithParseAccept(t *testing.T) {
+	headers := []string{
+		"text/html;Level=1;q=0.5;ext=\"a b\";flag, text/*;q=0",
+		"application/json;a=1;a=2, application/xml;charset=\"utf\\-8\"",
+	}
+	for _, bm := range benchmarkAccepts {
+		headers = append(headers, bm.accept)
+	}
+
+	for _, header := range headers {
+		expected, _ := ParseAccept(header)
+
+		var scanned []MediaRange
+		ScanAccept(header, ParseLimits{}, func(mr MediaRange) bool {
+			for name, value := range expected[len(scanned)].Params {
+				v, ok := mr.Param(name)
+				assert.True(t, ok, "%s %s", header, name)
+				assert.Equal(t, value, v, "%s %s", header, name)
+			}
+			for name, value := range expected[len(scanned)].Extensions {
+				v, ok := mr.Extension(name)
+				assert.True(t, ok, "%s %s", header, name)
+				assert.Equal(t, value, v, "%s %s", header, name)
+			}
+			assert.Equal(t, expected[len(scanned)].String(), mr.String(), header)
+			scanned = append(scanned, mr.withParamMaps())
+			return true
+		})
+
+		assert.Equal(t, expected, scanned, header)
+	}
+}
+
+func TestScanAcceptShouldStopWhenAsked(t *testing.T) {
+	var scanned []string
+	exceeded := ScanAccept("text/html, application/json, */*", ParseLimits{}, func(mr MediaRange) bool {
+		scanned = append(scanned, mr.MediaType())
+		return len(scanned) < 2
+	})
+
+	assert.False(t, exceeded)
+	assert.Equal(t, []string{"text/html", "application/json"}, scanned)
+}
+
+func TestParamShouldReportMissingParameters(t *testing.T) {
+	ranges, _ := ParseAccept("text/html;level=1;q=0.5")
+
+	_, ok := ranges[0].Param("charset")
+	assert.False(t, ok)
+	_, ok = ranges[0].Extension("level")
+	assert.False(t, ok)
+	level, ok := ranges[0].Param("level")
+	assert.True(t, ok)
+	assert.Equal(t, "1", level)
+}
+
+func TestMediaTypeShouldNotAllocateForParsedRanges(t *testing.T) {
+	ranges, _ := ParseAccept("application/json;q=0.5")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = ranges[0].MediaType()
+		_ = ranges[0].String()
+	})
+
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, "application/json", ranges[0].MediaType())
+}
+
+func TestMediaTypeShouldFollowChangedFields(t *testing.T) {
+	ranges, _ := ParseAccept("application/json")
+	mr := ranges[0]
+	mr.Subtype = "xml"
+
+	assert.Equal(t, "application/xml", mr.MediaType())
+}
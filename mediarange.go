@@ -0,0 +1,531 @@
+package negotiator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is a single element of an HTTP Accept header, parsed according to
+// the grammar in RFC 9110 section 12.5.1:
+//
+//	Accept = #( media-range [ weight ] )
+//	media-range = ( "*/*" / ( type "/" "*" ) / ( type "/" subtype ) ) parameters
+//	weight = OWS ";" OWS "q=" qvalue
+//
+// Parameters that follow the weight are accept extensions and are kept apart
+// from the media range parameters.
+type MediaRange struct {
+	// Type is the top-level type, e.g. "text" in "text/html". Case is preserved.
+	Type string
+	// Subtype is the subtype, e.g. "html" in "text/html". Case is preserved.
+	Subtype string
+	// Params holds the media range parameters, keyed by lower-case name. Quoted
+	// values are unquoted.
+	Params map[string]string
+	// Q is the quality value, 1.0 when not given explicitly.
+	Q float64
+	// Extensions holds any accept-ext parameters that follow the weight, keyed by
+	// lower-case name. Extensions without a value map to the empty string.
+	Extensions map[string]string
+
+	// weighted records whether a q parameter was present, valid or not.
+	weighted bool
+	// mediaType is the text of the type and subtype as parsed, so that MediaType
+	// need not build it again.
+	mediaType string
+	// params and extensions hold the text of the parameters and accept extensions of
+	// a media range parsed lazily, as by ScanAccept, in place of Params and Extensions.
+	params, extensions string
+}
+
+// ParseError describes a malformed element of an Accept header.
+type ParseError struct {
+	// Offset is the byte offset of the element within the header.
+	Offset int
+	// Element is the text of the offending element.
+	Element string
+	// Reason explains why the element was rejected.
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("negotiator: invalid media range %q at offset %d: %s", e.Element, e.Offset, e.Reason)
+}
+
+// MediaType returns the type and subtype without any parameters, e.g. "text/html".
+func (mr MediaRange) MediaType() string {
+	if m := mr.mediaType; len(m) == len(mr.Type)+1+len(mr.Subtype) &&
+		m[:len(mr.Type)] == mr.Type && m[len(mr.Type)+1:] == mr.Subtype {
+		return m
+	}
+	return mr.Type + "/" + mr.Subtype
+}
+
+// String returns the media range with its parameters (but not its weight or
+// extensions), e.g. "text/html;level=1". Parameters are written in name order.
+func (mr MediaRange) String() string {
+	params := mr.Params
+	if mr.params != "" {
+		params = textParamMap(mr.params, false)
+	}
+	return mr.MediaType() + formatParams(params, false)
+}
+
+// IsWildcard tests whether the media range is "*/*" or "type/*".
+func (mr MediaRange) IsWildcard() bool {
+	return mr.Type == "*" || mr.Subtype == "*"
+}
+
+// ParseAccept parses the value of an Accept header into its media ranges, in the
+// order they appear. Elements that cannot be parsed are left out of the result
+// and reported in the error list instead; a parameter that cannot be parsed is
+// dropped from its media range and reported likewise. An invalid q value is
+// reported and treated as 1.
+func ParseAccept(header string) ([]MediaRange, []*ParseError) {
+	ranges, errs, _ := ParseAcceptWithLimits(header, ParseLimits{})
+	return ranges, errs
+}
+
+// ParseAcceptWithLimits parses the value of an Accept header as ParseAccept does, but
+// stops at the limits: a header longer than MaxBytes is cut after the last whole
+// element that fits, media ranges beyond MaxRanges are left out, and so is any media
+// range with more than MaxParams parameters, since its q value may be among those
+// that would go unparsed. The final result reports whether any limit was exceeded.
+// Nothing beyond the limits is parsed.
+func ParseAcceptWithLimits(header string, limits ParseLimits) ([]MediaRange, []*ParseError, bool) {
+	header, cut := limits.cut(header)
+
+	size := strings.Count(header, ",") + 1
+	if limits.MaxRanges > 0 && size > limits.MaxRanges {
+		size = limits.MaxRanges
+	}
+
+	var errs []*ParseError
+	ranges, exceeded := appendMediaRanges(make([]MediaRange, 0, size), header, limits, false, &errs)
+	if len(ranges) == 0 {
+		ranges = nil
+	}
+	return ranges, errs, cut || exceeded
+}
+
+// ScanAccept parses the value of an Accept header as ParseAcceptWithLimits does, but
+// instead of collecting the media ranges it calls fn with each in turn, stopping early
+// if fn returns false. Malformed elements are skipped. The media ranges have nil Params
+// and Extensions: their parameters are kept as text, to be read with Param and
+// Extension, so that nothing is allocated unless a parameter name must be lower-cased
+// or a quoted string unescaped. It reports whether any limit was exceeded.
+func ScanAccept(header string, limits ParseLimits, fn func(MediaRange) bool) bool {
+	header, cut := limits.cut(header)
+	return scanMediaRanges(header, limits, true, nil, fn) || cut
+}
+
+// appendMediaRanges parses the Accept header as ParseAcceptWithLimits does, appending
+// the media ranges to dst, except that the header must already be cut to MaxBytes. If
+// lazy is set, parameters are kept as text, as ScanAccept does. Errors are appended to
+// errs unless it is nil.
+func appendMediaRanges(dst []MediaRange, header string, limits ParseLimits, lazy bool, errs *[]*ParseError) ([]MediaRange, bool) {
+	exceeded := scanMediaRanges(header, limits, lazy, errs, func(mr MediaRange) bool {
+		dst = append(dst, mr)
+		return true
+	})
+	return dst, exceeded
+}
+
+// scanMediaRanges calls fn with each media range of the Accept header, which must
+// already be cut to MaxBytes, until fn returns false. It reports whether MaxRanges or
+// MaxParams was exceeded.
+func scanMediaRanges(header string, limits ParseLimits, lazy bool, errs *[]*ParseError, fn func(MediaRange) bool) bool {
+	exceeded := false
+	count := 0
+	elements := listScanner{s: header, sep: ','}
+	for {
+		el, ok := elements.next()
+		if !ok {
+			return exceeded
+		}
+
+		el = el.trim(header)
+		if el.start == el.end {
+			continue // empty list elements are allowed and ignored
+		}
+
+		if count++; limits.MaxRanges > 0 && count > limits.MaxRanges {
+			return true
+		}
+
+		mr, ok, paramsExceeded := parseMediaRange(header[el.start:el.end], el.start, limits.MaxParams, lazy, errs)
+		exceeded = exceeded || paramsExceeded
+		if ok && !fn(mr) {
+			return exceeded
+		}
+	}
+}
+
+// parseMediaRange parses one element of an Accept header, at the offset given. If it has
+// more than maxParams parameters, unless that is 0, parsing stops and the element is
+// rejected, with true as the final result. If lazy is set, the parameters and accept
+// extensions are checked and kept as text rather than put in maps.
+func parseMediaRange(element string, offset int, maxParams int, lazy bool, errs *[]*ParseError) (MediaRange, bool, bool) {
+	fail := func(reason string) {
+		if errs != nil {
+			*errs = append(*errs, &ParseError{offset, element, reason})
+		}
+	}
+
+	parts := listScanner{s: element, sep: ';'}
+	first, _ := parts.next()
+	paramsStart, paramsEnd := first.end, len(element)
+	first = first.trim(element)
+	typeSubtype := element[first.start:first.end]
+	slash := strings.IndexByte(typeSubtype, '/')
+	if slash < 0 {
+		fail("missing '/' between type and subtype")
+		return MediaRange{}, false, false
+	}
+
+	mr := MediaRange{
+		Type:      typeSubtype[:slash],
+		Subtype:   typeSubtype[slash+1:],
+		Q:         1.0,
+		mediaType: typeSubtype,
+	}
+
+	switch {
+	case !isToken(mr.Type) || !isToken(mr.Subtype):
+		fail("type and subtype must be tokens")
+		return MediaRange{}, false, false
+	case mr.Type == "*" && mr.Subtype != "*":
+		fail("a wildcard type requires a wildcard subtype")
+		return MediaRange{}, false, false
+	}
+
+	params := 0
+	for {
+		part, ok := parts.next()
+		if !ok {
+			break
+		}
+
+		part = part.trim(element)
+		if part.start == part.end {
+			continue // e.g. "text/html;;level=1"
+		}
+
+		if params++; maxParams > 0 && params > maxParams {
+			return MediaRange{}, false, true
+		}
+
+		name, value, hasValue, reason := parseParam(element[part.start:part.end])
+		if reason != "" {
+			fail(reason)
+			continue
+		}
+
+		switch {
+		case name == "q" && !mr.weighted:
+			mr.weighted = true
+			if lazy {
+				paramsEnd = part.start
+				mr.extensions = element[part.end:]
+			}
+			q, ok := parseQValue(value)
+			if !ok {
+				fail("invalid q value " + strconv.Quote(value))
+				continue
+			}
+			mr.Q = q
+
+		case !hasValue && !mr.weighted:
+			fail("parameter " + strconv.Quote(name) + " has no value")
+
+		case lazy:
+			// kept as text
+
+		case mr.weighted:
+			if mr.Extensions == nil {
+				mr.Extensions = make(map[string]string)
+			}
+			mr.Extensions[name] = value
+
+		default:
+			if mr.Params == nil {
+				mr.Params = make(map[string]string)
+			}
+			mr.Params[name] = value
+		}
+	}
+
+	if lazy {
+		mr.params = element[paramsStart:paramsEnd]
+	}
+	return mr, true, false
+}
+
+// Param returns the value of the named media range parameter, which must be in lower
+// case, for media ranges given by ParseAccept and ScanAccept alike.
+func (mr MediaRange) Param(name string) (string, bool) {
+	return mr.lookup(false, name)
+}
+
+// Extension returns the value of the named accept extension, which must be in lower
+// case, for media ranges given by ParseAccept and ScanAccept alike.
+func (mr MediaRange) Extension(name string) (string, bool) {
+	return mr.lookup(true, name)
+}
+
+func (mr MediaRange) lookup(extensions bool, name string) (value string, found bool) {
+	mr.eachParam(extensions, func(n, v string) bool {
+		if n == name {
+			value, found = v, true
+			return false
+		}
+		return true
+	})
+	return value, found
+}
+
+// paramCount returns the number of media range parameters.
+func (mr MediaRange) paramCount() int {
+	count := 0
+	mr.eachParam(false, func(string, string) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// eachParam calls fn with the name and value of each media range parameter, or of each
+// accept extension, until fn returns false. They come from the maps, or if the media
+// range was parsed lazily, from its text.
+func (mr MediaRange) eachParam(extensions bool, fn func(name, value string) bool) {
+	m, text := mr.Params, mr.params
+	if extensions {
+		m, text = mr.Extensions, mr.extensions
+	}
+
+	if text != "" {
+		eachTextParam(text, extensions, fn)
+		return
+	}
+
+	for name, value := range m {
+		if !fn(name, value) {
+			return
+		}
+	}
+}
+
+// eachTextParam calls fn with each parameter held as text that parsing into a map would
+// keep, until fn returns false.
+func eachTextParam(text string, extensions bool, fn func(name, value string) bool) {
+	parts := listScanner{s: text, sep: ';'}
+	for part, ok := parts.next(); ok; part, ok = parts.next() {
+		name, value, ok := textParam(text, part, extensions)
+		if ok && !paramOverridden(text[part.end:], name, extensions) && !fn(name, value) {
+			return
+		}
+	}
+}
+
+// textParam parses a parameter held as text, reporting whether it is one that parsing
+// into maps would keep: media range parameters need a value, accept extensions do not.
+func textParam(text string, part span, extensions bool) (name, value string, ok bool) {
+	part = part.trim(text)
+	if part.start == part.end {
+		return "", "", false
+	}
+	name, value, hasValue, reason := parseParam(text[part.start:part.end])
+	return name, value, reason == "" && (hasValue || extensions)
+}
+
+// paramOverridden tests whether a later parameter of the same name follows, which would
+// replace this one in a map.
+func paramOverridden(rest, name string, extensions bool) bool {
+	parts := listScanner{s: rest, sep: ';'}
+	for part, ok := parts.next(); ok; part, ok = parts.next() {
+		if n, _, ok := textParam(rest, part, extensions); ok && n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withParamMaps returns the media range with its parameters and accept extensions in
+// Params and Extensions, as ParseAccept gives them, if it was parsed lazily.
+func (mr MediaRange) withParamMaps() MediaRange {
+	if mr.params != "" {
+		mr.Params = textParamMap(mr.params, false)
+		mr.params = ""
+	}
+	if mr.extensions != "" {
+		mr.Extensions = textParamMap(mr.extensions, true)
+		mr.extensions = ""
+	}
+	return mr
+}
+
+func textParamMap(text string, extensions bool) map[string]string {
+	var m map[string]string
+	eachTextParam(text, extensions, func(name, value string) bool {
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[name] = value
+		return true
+	})
+	return m
+}
+
+// parseParam splits a parameter into its lower-cased name and its value,
+// unquoting quoted strings. Whitespace around "=" is tolerated. A non-empty
+// reason is returned if the parameter is malformed.
+func parseParam(param string) (name, value string, hasValue bool, reason string) {
+	eq := strings.IndexByte(param, '=')
+	if eq < 0 {
+		name = strings.ToLower(param)
+	} else {
+		name = strings.ToLower(strings.TrimSpace(param[:eq]))
+		value = strings.TrimSpace(param[eq+1:])
+		hasValue = true
+	}
+
+	if !isToken(name) {
+		return "", "", false, "parameter name " + strconv.Quote(name) + " is not a token"
+	}
+
+	if !hasValue {
+		return name, "", false, ""
+	}
+
+	if strings.HasPrefix(value, `"`) {
+		unquoted, ok := unquote(value)
+		if !ok {
+			return "", "", false, "malformed quoted string for parameter " + strconv.Quote(name)
+		}
+		return name, unquoted, true, ""
+	}
+
+	if !isToken(value) {
+		return "", "", false, "value of parameter " + strconv.Quote(name) + " is not a token"
+	}
+
+	return name, value, true, ""
+}
+
+// parseQValue parses a qvalue, which must lie between 0 and 1 inclusive.
+func parseQValue(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	for i := 0; i < len(value); i++ {
+		if (value[i] < '0' || value[i] > '9') && value[i] != '.' {
+			return 0, false
+		}
+	}
+
+	q, err := strconv.ParseFloat(value, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, false
+	}
+
+	return q, true
+}
+
+// unquote removes the quotes and quoted-pair escapes from a quoted-string.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+
+	if strings.IndexByte(s[1:len(s)-1], '\\') < 0 && strings.IndexByte(s[1:len(s)-1], '"') < 0 {
+		return s[1 : len(s)-1], true
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(s)-1 {
+				return "", false // the escape consumed the closing quote
+			}
+			b.WriteByte(s[i])
+		case c == '"':
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), true
+}
+
+// isToken tests whether s is a non-empty RFC 9110 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTokenChar(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// formatParams writes parameters in name order as ";name=value", quoting values
+// that are not tokens. If bare is set, parameters with an empty value are written
+// without "=", as accept extensions may be.
+func formatParams(params map[string]string, bare bool) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteByte(';')
+		b.WriteString(name)
+		if value := params[name]; value != "" || !bare {
+			b.WriteByte('=')
+			b.WriteString(quoteIfNeeded(value))
+		}
+	}
+
+	return b.String()
+}
+
+func quoteIfNeeded(value string) string {
+	if isToken(value) {
+		return value
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
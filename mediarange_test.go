@@ -0,0 +1,98 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccept_parses_type_subtype_and_params(t *testing.T) {
+	mr, errs := ParseAccept("text/html;level=1;charset=UTF-8")
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 1, len(mr))
+	assert.Equal(t, "text", mr[0].Type)
+	assert.Equal(t, "html", mr[0].Subtype)
+	assert.Equal(t, map[string]string{"level": "1", "charset": "UTF-8"}, mr[0].Params)
+	assert.Equal(t, 1.0, mr[0].Q)
+	assert.Equal(t, "text/html;charset=UTF-8;level=1", mr[0].String())
+}
+
+func TestParseAccept_keeps_quoted_separators(t *testing.T) {
+	mr, errs := ParseAccept(`application/ld+json;profile="a,b;c";q=0.5, text/plain`)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 2, len(mr))
+	assert.Equal(t, "a,b;c", mr[0].Params["profile"])
+	assert.Equal(t, 0.5, mr[0].Q)
+	assert.Equal(t, `application/ld+json;profile="a,b;c"`, mr[0].String())
+	assert.Equal(t, "text/plain", mr[1].MediaType())
+}
+
+func TestParseAccept_unescapes_quoted_pairs(t *testing.T) {
+	mr, errs := ParseAccept(`text/plain;x="a\"b\\c"`)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, `a"b\c`, mr[0].Params["x"])
+	assert.Equal(t, `text/plain;x="a\"b\\c"`, mr[0].String())
+}
+
+func TestParseAccept_tolerates_whitespace(t *testing.T) {
+	mr, errs := ParseAccept("text/html ; Q = 0.7 ,  text/* ;level = 2")
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 2, len(mr))
+	assert.Equal(t, 0.7, mr[0].Q)
+	assert.Equal(t, "2", mr[1].Params["level"])
+}
+
+func TestParseAccept_separates_accept_extensions(t *testing.T) {
+	mr, errs := ParseAccept("text/html;level=1;q=0.5;a=1;b")
+
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]string{"level": "1"}, mr[0].Params)
+	assert.Equal(t, map[string]string{"a": "1", "b": ""}, mr[0].Extensions)
+}
+
+func TestParseAccept_ignores_empty_elements(t *testing.T) {
+	mr, errs := ParseAccept(" , text/html,, ")
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 1, len(mr))
+}
+
+func TestParseAccept_reports_errors(t *testing.T) {
+	var errorTests = []struct {
+		header   string
+		expected int
+	}{
+		{"text", 0},
+		{"*/html", 0},
+		{"text/ht ml", 0},
+		{`text/html;a="b`, 1},
+		{"text/html;q=2", 1},
+		{"text/html;q=blah", 1},
+		{"text/html;level", 1},
+		{"text/html;a=b c", 1},
+	}
+
+	for _, tt := range errorTests {
+		mr, errs := ParseAccept(tt.header)
+		assert.Equal(t, tt.expected, len(mr), "ranges from "+tt.header)
+		assert.Equal(t, 1, len(errs), "errors from "+tt.header)
+	}
+}
+
+func TestParseAccept_reports_error_offsets(t *testing.T) {
+	_, errs := ParseAccept("text/html,  bogus")
+
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, 12, errs[0].Offset)
+	assert.Equal(t, "bogus", errs[0].Element)
+}
+
+func TestParseAccept_defaults_invalid_quality(t *testing.T) {
+	mr, _ := ParseAccept("text/html;q=blah")
+
+	assert.Equal(t, 1.0, mr[0].Q)
+}
@@ -15,6 +15,8 @@
 package negotiator
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strings"
 )
@@ -25,38 +27,124 @@ const (
 )
 
 // Negotiator is responsible for content negotiation when using custom response processors.
-type Negotiator struct{ processors []ResponseProcessor }
+//
+// A Negotiator is immutable once constructed: the methods that configure it return a new
+// Negotiator and leave the original unchanged. It is therefore safe for concurrent use
+// by multiple goroutines, provided its processors are too.
+type Negotiator struct {
+	processors       []ResponseProcessor
+	qualities        []float64
+	variants         []variant // computed from processors and qualities by With
+	mode             Mode
+	notAcceptable    NotAcceptableHandler
+	fallback         FallbackPolicy
+	defaultProcessor ResponseProcessor
+	vary             []string
+	trace            bool
+	languages        []string
+	charsets         []string
+	bom              bool
+	encodings        []string
+	compressMinSize  int
+	decoders         []RequestProcessor
+	decodeChain      []RequestProcessor // decoders then the built-in ones, computed by With
+	decodeOptions    DecodeOptions
+	buffered         bool
+	noAjax           bool
+	noBuiltIns       bool
+	onDecision       DecisionHook
+	onError          ErrorHook
+	cacheSize        int
+	cache            *decisionCache // made afresh by With
+	parseLimits      ParseLimits
+	overflow         OverflowPolicy
+}
 
 // NewWithJSONAndXML allows users to pass custom response processors. By default, processors
-// for XML and JSON are already created.
+// for XML and JSON are already created. Like every Negotiator, it has the built-in request
+// processors that the package-level Decode uses.
 func NewWithJSONAndXML(responseProcessors ...ResponseProcessor) *Negotiator {
-	return New(append(responseProcessors, NewJSON(), NewXML())...)
+	return NewNegotiator(
+		WithProcessors(responseProcessors...),
+		WithProcessors(NewJSON(), NewXML()),
+	)
 }
 
 //New allows users to pass custom response processors.
 func New(responseProcessors ...ResponseProcessor) *Negotiator {
-	return &Negotiator{
-		responseProcessors,
-	}
+	return NewNegotiator(WithProcessors(responseProcessors...))
 }
 
 // Add more response processors. A new Negotiator is returned with the original processors plus
 // the extra processors.
 func (n *Negotiator) Add(responseProcessors ...ResponseProcessor) *Negotiator {
-	return &Negotiator{
-		append(n.processors, responseProcessors...),
-	}
+	return n.With(WithProcessors(responseProcessors...))
+}
+
+// AddWithQuality adds more response processors, each with the server quality qs, between
+// 0 and 1. The quality of a response processor is multiplied by the quality that the
+// client gives the media range it matched, so that where the client has no preference
+// (as with "*/*", or equal q values) the processor with the higher qs is chosen. Processors
+// added by New or Add have a server quality of 1. A qs outside 0 to 1 is clamped to that
+// range, and NaN is treated as 1, as an invalid q value is. A new Negotiator is returned
+// with the original processors plus the extra processors.
+func (n *Negotiator) AddWithQuality(qs float64, responseProcessors ...ResponseProcessor) *Negotiator {
+	return n.With(WithServerQuality(qs, responseProcessors...))
+}
+
+// WithMode chooses the algorithm used to select a response processor. A new Negotiator
+// is returned with the same processors as the original.
+func (n *Negotiator) WithMode(mode Mode) *Negotiator {
+	return n.With(WithMode(mode))
 }
 
-// Negotiate your model based on the HTTP Accept header.
+// Negotiate your model based on the HTTP Accept header. A model with a StatusCode() int
+// method, such as *DecodeError, is sent with that status.
 func (n *Negotiator) Negotiate(w http.ResponseWriter, req *http.Request, dataModel interface{}, context ...interface{}) error {
-	return negotiateHeader(n.processors, w, req, dataModel, context...)
+	return n.negotiateHeader(w, req, ResponseOptions{}, dataModel, context...)
 }
 
+// defaultNegotiator serves the package-level Negotiate and Respond. Being immutable, one
+// Negotiator can be shared by every request.
+var defaultNegotiator = New(NewJSON(), NewXML())
+
 // Negotiate your model based on the HTTP Accept header. Only XML and JSON are handled.
 func Negotiate(w http.ResponseWriter, req *http.Request, dataModel interface{}, context ...interface{}) error {
-	processors := []ResponseProcessor{NewJSON(), NewXML()}
-	return negotiateHeader(processors, w, req, dataModel, context...)
+	return defaultNegotiator.negotiateHeader(w, req, ResponseOptions{}, dataModel, context...)
+}
+
+// ResponseOptions control the status and headers of a negotiated response (see Respond).
+type ResponseOptions struct {
+	// Status is the status of the response, such as 201 (Created). If it is 0, the
+	// status is that of the model if it has a StatusCode() int method, and otherwise
+	// that chosen by the response processor: normally 200, or 204 for a nil model. A
+	// status that forbids a body, 1xx, 204 (No Content) or 304 (Not Modified), is sent
+	// with the headers alone: the response processor does not run, whatever the model.
+	Status int
+	// Header holds extra response headers, such as Location. They are added before the
+	// response processor runs, so the processor's own headers, such as Content-Type,
+	// take precedence.
+	Header http.Header
+	// Template names the template with which to render the model, for response
+	// processors that use templates (see RenderContext).
+	Template string
+	// Values are passed to the response processor in the RenderContext. As with
+	// context.Context, keys should be of types of your own to avoid collisions.
+	Values map[interface{}]interface{}
+}
+
+// Respond negotiates like Negotiate, but sends the response with the status and extra
+// headers given in the options, whichever response processor is chosen, and passes the
+// template and values to processors that implement ContextProcessor. The options are
+// not applied if negotiation fails with 406 (Not Acceptable) or 300 (Multiple Choices).
+func (n *Negotiator) Respond(w http.ResponseWriter, req *http.Request, options ResponseOptions, dataModel interface{}, context ...interface{}) error {
+	return n.negotiateHeader(w, req, options, dataModel, context...)
+}
+
+// Respond negotiates like Negotiate, with the status and extra headers given in the
+// options (see Negotiator.Respond). Only XML and JSON are handled.
+func Respond(w http.ResponseWriter, req *http.Request, options ResponseOptions, dataModel interface{}, context ...interface{}) error {
+	return defaultNegotiator.negotiateHeader(w, req, options, dataModel, context...)
 }
 
 // Firstly, all Ajax requests are processed by the first available Ajax processor.
@@ -70,46 +158,225 @@ func Negotiate(w http.ResponseWriter, req *http.Request, dataModel interface{},
 // acceptable, the origin server can either honour the header field by
 // sending a 406 (Not Acceptable) response or disregard the header field
 // by treating the response as if it is not subject to content negotiation.
-// This implementation prefers the former.
+// The FallbackPolicy decides which; by default this implementation prefers
+// the former, writing the response with the NotAcceptableHandler and
+// returning a *NotAcceptableError. A 300 (Multiple Choices) response returns
+// the *NotAcceptableError too, since no representation was sent. Any other
+// error from Decide is sent as problem details (see Negotiator.Error).
 //
 // See rfc7231-sec5.3.2:
 // http://tools.ietf.org/html/rfc7231#section-5.3.2
-func negotiateHeader(processors []ResponseProcessor, w http.ResponseWriter, req *http.Request, dataModel interface{}, context ...interface{}) error {
-	if IsAjax(req) {
-		for _, processor := range processors {
-			ajax, doesAjax := processor.(AjaxResponseProcessor)
-			if doesAjax && ajax.IsAjaxResponder() {
-				return processor.Process(w, req, dataModel, context...)
-			}
+func (n *Negotiator) negotiateHeader(w http.ResponseWriter, req *http.Request, options ResponseOptions, dataModel interface{}, context ...interface{}) error {
+	d, err := n.Decide(req)
+	if n.onDecision != nil {
+		n.onDecision(req, d)
+	}
+	addVary(w.Header(), d.Vary...)
+	if n.trace {
+		writeTrace(w.Header(), d)
+	}
+
+	if err == nil {
+		err = n.render(w, req, d, options, dataModel, context...)
+		if err != nil && n.onError != nil {
+			n.onError(req, err)
+		}
+		return err
+	}
+
+	if n.onError != nil {
+		n.onError(req, err)
+	}
+
+	var nae *NotAcceptableError
+	if !errors.As(err, &nae) {
+		// such as a *ParseLimitError
+		n.Error(w, req, err)
+		return err
+	}
+
+	if n.fallbackPolicy(req) == FallbackMultipleChoices {
+		writeMultipleChoices(w, req, nae)
+		return err
+	}
+
+	handler := n.notAcceptable
+	if handler == nil {
+		handler = DefaultNotAcceptableHandler
+	}
+	handler(w, req, nae)
+	return err
+}
+
+// render has the chosen processor write the response, with the headers of the chosen
+// representation and those in the options, through writers that transcode and compress
+// its output as the Decision requires, and that impose the status if one is given. With
+// buffering, nothing is sent unless the processor succeeds, not even those headers.
+func (n *Negotiator) render(w http.ResponseWriter, req *http.Request, d Decision, options ResponseOptions, dataModel interface{}, context ...interface{}) error {
+	var closers []func() error
+
+	var bw *bufferedWriter
+	if n.buffered {
+		bw = newBufferedWriter(w)
+		w = bw
+	}
+
+	if d.Language != "" {
+		w.Header().Set("Content-Language", d.Language)
+		req = withLanguage(req, d.Language)
+	}
+	for name, values := range options.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	status := options.Status
+	if sc, ok := dataModel.(statusCoder); ok && status == 0 {
+		status = sc.StatusCode()
+	}
+	if !bodyAllowed(status) {
+		w.WriteHeader(status)
+		if bw != nil {
+			return bw.commit()
 		}
+		return nil
+	}
+
+	if d.Encoding != "" && d.Encoding != EncodingIdentity {
+		cw := newCompressWriter(w, d.Encoding, n.compressMinSize)
+		w = cw
+		closers = append(closers, cw.close)
+	}
+
+	if d.Charset != "" {
+		cw := newCharsetWriter(w, d.Charset, n.bom)
+		w = cw
+		closers = append(closers, cw.close)
 	}
 
-	accept := accept(req.Header.Get("Accept"))
+	if status != 0 {
+		w = &statusWriter{ResponseWriter: w, status: status}
+	}
+
+	var err error
+	if cp, ok := d.Processor.(ContextProcessor); ok {
+		err = cp.ProcessWithContext(w, req, dataModel, newRenderContext(d, status, options, context))
+	} else {
+		err = d.Processor.Process(w, req, dataModel, context...)
+	}
 
-	if len(processors) > 0 {
-		if accept == "" {
-			return processors[0].Process(w, req, dataModel, context...)
+	for i := len(closers) - 1; i >= 0; i-- {
+		if cerr := closers[i](); err == nil {
+			err = cerr
 		}
+	}
 
-		for _, mr := range accept.ParseMediaRanges() {
-			if len(mr.Value) == 0 {
-				continue
-			}
+	if bw != nil {
+		if err != nil {
+			bw.release()
+			return err
+		}
+		return bw.commit()
+	}
+	return err
+}
 
-			if strings.EqualFold(mr.Value, "*/*") {
-				return processors[0].Process(w, req, dataModel, context...)
-			}
+// statusCoder is implemented by models, such as *DecodeError, that are rendered with a
+// status of their own.
+type statusCoder interface {
+	StatusCode() int
+}
+
+// bodyAllowed tests whether a response with the status may have a body (RFC 9110
+// section 6.4.1). A status of 0 is left to the response processor, so it may.
+func bodyAllowed(status int) bool {
+	return status == 0 || status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
 
-			for _, processor := range processors {
-				if processor.CanProcess(mr.Value) {
-					return processor.Process(w, req, dataModel, context...)
-				}
-			}
+// statusWriter sends the response with a given status, whatever the response processor
+// asks for.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(int) {
+	if !sw.wroteHeader {
+		sw.wroteHeader = true
+		sw.ResponseWriter.WriteHeader(sw.status)
+	}
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.WriteHeader(sw.status)
+	return sw.ResponseWriter.Write(p)
+}
+
+// ajaxResponder returns the index of the first processor that handles Ajax requests,
+// or -1 if there is none.
+func (n *Negotiator) ajaxResponder() int {
+	if n.noAjax {
+		return -1
+	}
+	for i, processor := range n.processors {
+		ajax, doesAjax := processor.(AjaxResponseProcessor)
+		if doesAjax && ajax.IsAjaxResponder() {
+			return i
 		}
 	}
+	return -1
+}
+
+// canProcess asks the processor whether it can handle the media range, using the
+// parsed form if the processor supports it.
+func canProcess(processor ResponseProcessor, mr MediaRange) bool {
+	if mrp, ok := processor.(MediaRangeProcessor); ok {
+		return mrp.CanProcessMediaRange(mr.withParamMaps())
+	}
+	return processor.CanProcess(legacyValue(mr))
+}
+
+// uniformQualities gives n processors the server quality qs, made valid.
+func uniformQualities(n int, qs float64) []float64 {
+	switch {
+	case math.IsNaN(qs):
+		qs = 1.0
+	case qs < 0:
+		qs = 0
+	case qs > 1:
+		qs = 1.0
+	}
+
+	qualities := make([]float64, n)
+	for i := range qualities {
+		qualities[i] = qs
+	}
+	return qualities
+}
+
+// combinedHeader returns all the field values of a header as a single comma-separated
+// list, as though the field had been sent on one line. Senders and proxies are allowed
+// to split a list-valued field across several lines (RFC 9110 section 5.3), so reading
+// only the first line would lose information. Empty field values are skipped.
+func combinedHeader(header http.Header, name string) string {
+	values := header[http.CanonicalHeaderKey(name)]
 
-	http.Error(w, "", http.StatusNotAcceptable)
-	return nil
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0]
+	}
+
+	combined := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			combined = append(combined, v)
+		}
+	}
+	return strings.Join(combined, ", ")
 }
 
 // IsAjax tests whether a request has the Ajax header.
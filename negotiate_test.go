@@ -122,3 +122,84 @@ func (*fakeProcessor) Process(w http.ResponseWriter, req *http.Request, model in
 	w.Write([]byte("boo ya!"))
 	return nil
 }
+
+func TestShouldOfferParsedMediaRangesToMediaRangeProcessors(t *testing.T) {
+	var rangeProcessor = &fakeMediaRangeProcessor{}
+	negotiator := New(rangeProcessor)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", `application/ld+json;profile="https://example.com/a,b", application/json`)
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "https://example.com/a,b", recorder.Body.String())
+}
+
+type fakeMediaRangeProcessor struct {
+	fakeProcessor
+	profile string
+}
+
+func (p *fakeMediaRangeProcessor) CanProcessMediaRange(mr MediaRange) bool {
+	p.profile = mr.Params["profile"]
+	return mr.Subtype == "ld+json"
+}
+
+func (p *fakeMediaRangeProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	w.Write([]byte(p.profile))
+	return nil
+}
+
+func TestShouldCombineAcceptHeaderLines(t *testing.T) {
+	var headerTests = []struct {
+		lines    []string
+		expected string
+	}{
+		// a client splitting its list over two lines
+		{[]string{"image/png", "application/negotiatortesting"}, "boo ya!"},
+		// a proxy appending a line after the client's own
+		{[]string{"image/png;q=0.9, image/gif", "application/negotiatortesting;q=0.1"}, "boo ya!"},
+		// an empty line contributes nothing
+		{[]string{"", "application/negotiatortesting"}, "boo ya!"},
+	}
+
+	negotiator := New(&fakeProcessor{})
+
+	for _, tt := range headerTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		for _, line := range tt.lines {
+			req.Header.Add("Accept", line)
+		}
+		recorder := httptest.NewRecorder()
+
+		negotiator.Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, tt.expected, recorder.Body.String(), strings.Join(tt.lines, " | "))
+	}
+}
+
+func TestShouldCombineAcceptHeaderLinesInRFC9110Mode(t *testing.T) {
+	negotiator := New(NewJSON(), NewTXT()).WithMode(RFC9110Mode)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/json;q=0.5")
+	req.Header.Add("Accept", "text/plain")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "text/plain", recorder.HeaderMap.Get("Content-Type"))
+}
+
+func TestCombinedHeader(t *testing.T) {
+	header := http.Header{}
+	assert.Equal(t, "", combinedHeader(header, "Accept"))
+
+	header.Add("Accept", "text/html")
+	assert.Equal(t, "text/html", combinedHeader(header, "accept"))
+
+	header.Add("Accept", " ")
+	header.Add("Accept", "text/plain;q=0.5")
+	assert.Equal(t, "text/html, text/plain;q=0.5", combinedHeader(header, "Accept"))
+}
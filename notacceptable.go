@@ -0,0 +1,155 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// ErrNotAcceptable is the error underlying every NotAcceptableError, so callers can test
+// for a 406 outcome with errors.Is.
+var ErrNotAcceptable = errors.New("negotiator: not acceptable")
+
+// NotAcceptableError is returned by Negotiate when none of the response processors can
+// produce a representation that the client finds acceptable. By the time it is returned,
+// the NotAcceptableHandler has already written the response.
+type NotAcceptableError struct {
+	// Accept holds the media ranges parsed from the request's Accept header.
+	Accept []MediaRange
+	// Offers holds the media types declared by the response processors.
+	Offers []string
+	// Charsets holds the available charsets if it was the charset that was not
+	// acceptable, and is otherwise empty.
+	Charsets []string
+	// Encodings holds the available content codings if it was the content coding that
+	// was not acceptable, and is otherwise empty.
+	Encodings []string
+}
+
+func (e *NotAcceptableError) Error() string {
+	ranges := make([]string, len(e.Accept))
+	for i, mr := range e.Accept {
+		ranges[i] = mr.String()
+	}
+	msg := ErrNotAcceptable.Error() + ": accept " + strings.Join(ranges, ", ") +
+		"; offered " + strings.Join(e.Offers, ", ")
+	if len(e.Charsets) > 0 {
+		msg += "; charsets " + strings.Join(e.Charsets, ", ")
+	}
+	if len(e.Encodings) > 0 {
+		msg += "; encodings " + strings.Join(e.Encodings, ", ")
+	}
+	return msg
+}
+
+// Unwrap returns ErrNotAcceptable.
+func (e *NotAcceptableError) Unwrap() error {
+	return ErrNotAcceptable
+}
+
+// NotAcceptableHandler writes the response when negotiation fails.
+type NotAcceptableHandler func(w http.ResponseWriter, req *http.Request, err *NotAcceptableError)
+
+// WithNotAcceptableHandler installs a handler to write the response when no response
+// processor is acceptable. A nil handler restores DefaultNotAcceptableHandler. A new
+// Negotiator is returned with the same processors as the original.
+func (n *Negotiator) WithNotAcceptableHandler(handler NotAcceptableHandler) *Negotiator {
+	return n.With(WithNotAcceptableHandler(handler))
+}
+
+// notAcceptableError describes the failed negotiation of req.
+func (n *Negotiator) notAcceptableError(req *http.Request) *NotAcceptableError {
+	ranges, _ := n.parseAccept(nil, combinedHeader(req.Header, "Accept"), false)
+
+	offers := []string{}
+	for _, processor := range n.processors {
+		for _, offer := range offersOf(processor) {
+			offers = append(offers, offer.String())
+		}
+	}
+
+	return &NotAcceptableError{Accept: ranges, Offers: offers}
+}
+
+var alternativesFormats = []MediaRange{
+	{Type: "text", Subtype: "plain", Q: 1.0},
+	{Type: "text", Subtype: "html", Q: 1.0},
+	{Type: "application", Subtype: "json", Q: 1.0},
+}
+
+// DefaultNotAcceptableHandler responds with 406 Not Acceptable and a body listing the
+// supported media types. The body is written as plain text, HTML or JSON, whichever the
+// client prefers, or as plain text if it accepts none of them.
+func DefaultNotAcceptableHandler(w http.ResponseWriter, req *http.Request, err *NotAcceptableError) {
+	writeAlternatives(w, http.StatusNotAcceptable, "Supported media types:", err)
+}
+
+// writeAlternatives responds with the status and a body listing the offered media types,
+// and any charsets or content codings, in the format the client prefers.
+func writeAlternatives(w http.ResponseWriter, status int, caption string, err *NotAcceptableError) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	format := 0
+	if chosen, ok := selectVariant(offerVariants(alternativesFormats), err.Accept, RFC9110Mode, nil); ok {
+		format = chosen.index
+	}
+
+	title := http.StatusText(status)
+	sections := []struct {
+		caption string
+		items   []string
+	}{
+		{caption, err.Offers},
+		{"Supported charsets:", err.Charsets},
+		{"Supported encodings:", err.Encodings},
+	}
+
+	switch alternativesFormats[format].Subtype {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		var b strings.Builder
+		b.WriteString("<!DOCTYPE html>\n<html><head><title>" + title + "</title></head><body>\n")
+		b.WriteString("<h1>" + title + "</h1>\n")
+		for i, section := range sections {
+			if i > 0 && len(section.items) == 0 {
+				continue
+			}
+			b.WriteString("<p>" + section.caption + "</p>\n<ul>\n")
+			for _, item := range section.items {
+				b.WriteString("<li>" + html.EscapeString(item) + "</li>\n")
+			}
+			b.WriteString("</ul>\n")
+		}
+		b.WriteString("</body></html>\n")
+		w.Write([]byte(b.String()))
+
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(struct {
+			Error     string   `json:"error"`
+			Supported []string `json:"supported"`
+			Charsets  []string `json:"charsets,omitempty"`
+			Encodings []string `json:"encodings,omitempty"`
+		}{title, err.Offers, err.Charsets, err.Encodings})
+
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		var b strings.Builder
+		b.WriteString(title + "\n")
+		for i, section := range sections {
+			if i > 0 && len(section.items) == 0 {
+				continue
+			}
+			b.WriteString("\n" + section.caption + "\n")
+			for _, item := range section.items {
+				b.WriteString(item + "\n")
+			}
+		}
+		w.Write([]byte(b.String()))
+	}
+}
@@ -0,0 +1,73 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldReturnNotAcceptableError(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML())
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png, image/*;q=0.5")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	assert.True(t, errors.Is(err, ErrNotAcceptable))
+
+	var nae *NotAcceptableError
+	assert.True(t, errors.As(err, &nae))
+	assert.Equal(t, 2, len(nae.Accept))
+	assert.Equal(t, "image/*", nae.Accept[1].String())
+	assert.Equal(t, []string{"application/json", "application/xml"}, nae.Offers)
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
+
+func TestDefaultNotAcceptableHandlerBodies(t *testing.T) {
+	var bodyTests = []struct {
+		acceptheader string
+		contentType  string
+		body         string
+	}{
+		{"image/png", "text/plain; charset=utf-8", "Not Acceptable\n\nSupported media types:\napplication/xml\n"},
+		{"image/png, text/*", "text/plain; charset=utf-8", "Not Acceptable\n\nSupported media types:\napplication/xml\n"},
+		{"text/html", "text/html; charset=utf-8", "<li>application/xml</li>"},
+		{"application/json;q=0.5, image/png", "application/json", `{"error":"Not Acceptable","supported":["application/xml"]}`},
+	}
+
+	negotiator := New(NewXML())
+
+	for _, tt := range bodyTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", tt.acceptheader)
+		recorder := httptest.NewRecorder()
+
+		negotiator.Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, http.StatusNotAcceptable, recorder.Code, tt.acceptheader)
+		assert.Equal(t, tt.contentType, recorder.HeaderMap.Get("Content-Type"), tt.acceptheader)
+		assert.Contains(t, recorder.Body.String(), tt.body, tt.acceptheader)
+	}
+}
+
+func TestShouldUseCustomNotAcceptableHandler(t *testing.T) {
+	var handled *NotAcceptableError
+	negotiator := New(NewJSON()).WithNotAcceptableHandler(func(w http.ResponseWriter, req *http.Request, err *NotAcceptableError) {
+		handled = err
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, handled, err)
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+}
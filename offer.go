@@ -0,0 +1,132 @@
+package negotiator
+
+import "strings"
+
+// variant is one representation that can be negotiated: one of the media types
+// declared by a processor, whatever the processor's CanProcess method will accept
+// (when offer is nil), or a bare media type (when processor is nil).
+type variant struct {
+	// index is the position of the processor, or of the bare media type.
+	index     int
+	processor ResponseProcessor
+	offer     *MediaRange
+	// qs is the server quality of the processor and declared media type combined.
+	qs float64
+	// declared tells whether the processor declares any valid media types.
+	declared bool
+}
+
+// offersOf returns the media types declared by the processor, if it declares any.
+// Declared media types that are malformed or contain wildcards are ignored.
+func offersOf(processor ResponseProcessor) []MediaRange {
+	mtp, ok := processor.(MediaTypeProcessor)
+	if !ok {
+		return nil
+	}
+
+	var offers []MediaRange
+	for _, mediaType := range mtp.MediaTypes() {
+		if offer := parseOffer(mediaType); offer != nil {
+			offers = append(offers, *offer)
+		}
+	}
+	return offers
+}
+
+// parseOffer parses a concrete media type, returning nil if it is malformed or
+// contains wildcards. A qs parameter gives its server quality, which is held in Q.
+func parseOffer(mediaType string) *MediaRange {
+	offer, ok, _ := parseMediaRange(strings.TrimSpace(mediaType), 0, 0, false, nil)
+	if !ok || offer.IsWildcard() {
+		return nil
+	}
+
+	if qs, ok := offer.Params["qs"]; ok {
+		delete(offer.Params, "qs")
+		if offer.Q, ok = parseQValue(qs); !ok {
+			offer.Q = 1.0
+		}
+	}
+
+	return &offer
+}
+
+// offerType returns the media type of an offer, or the empty string if there is none.
+func offerType(offer *MediaRange) string {
+	if offer == nil {
+		return ""
+	}
+	return offer.MediaType()
+}
+
+// processorVariants lists every representation available from the processors, in
+// processor order. Each processor's declared media types come first, followed by a
+// variant that defers to its CanProcess method.
+func processorVariants(processors []ResponseProcessor, qualities []float64) []variant {
+	var vs []variant
+	for i, processor := range processors {
+		offers := offersOf(processor)
+		declared := len(offers) > 0
+		for k := range offers {
+			vs = append(vs, variant{i, processor, &offers[k], qualities[i] * offers[k].Q, declared})
+		}
+		vs = append(vs, variant{i, processor, nil, qualities[i], declared})
+	}
+	return vs
+}
+
+// offerVariants lists bare media types as variants.
+func offerVariants(offers []MediaRange) []variant {
+	vs := make([]variant, len(offers))
+	for i := range offers {
+		vs[i] = variant{i, nil, &offers[i], offers[i].Q, true}
+	}
+	return vs
+}
+
+// matches tests whether the media range selects this variant. Declared media types
+// are matched centrally, including wildcards and parameters. Otherwise the
+// processor's CanProcess method decides, except that "*/*" matches any processor
+// that does not declare its media types. A processor whose declared media types are
+// all invalid is treated as declaring none.
+func (v variant) matches(mr MediaRange) bool {
+	if v.offer != nil {
+		return offerMatches(*v.offer, mr)
+	}
+
+	if v.declared && mr.IsWildcard() {
+		return false
+	}
+
+	return mr.Type == "*" || canProcess(v.processor, mr)
+}
+
+// offerMatches tests whether an offered media type falls within a media range. The
+// type and subtype are compared case-insensitively, and every parameter of the range
+// must be present in the offer with the same value; the offer may have others.
+func offerMatches(offer, mr MediaRange) bool {
+	if mr.Type != "*" && !strings.EqualFold(offer.Type, mr.Type) {
+		return false
+	}
+
+	if mr.Subtype != "*" && !strings.EqualFold(offer.Subtype, mr.Subtype) {
+		return false
+	}
+
+	matches := true
+	mr.eachParam(false, func(name, value string) bool {
+		offered, ok := offer.Params[name]
+		matches = ok && paramValueEqual(name, offered, value)
+		return matches
+	})
+	return matches
+}
+
+// paramValueEqual compares parameter values, which are case-sensitive unless the
+// parameter is defined otherwise, as charset is.
+func paramValueEqual(name, a, b string) bool {
+	if name == "charset" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
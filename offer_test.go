@@ -0,0 +1,125 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfferMatches(t *testing.T) {
+	var matchTests = []struct {
+		offer, mediaRange string
+		expected          bool
+	}{
+		{"application/json", "application/json", true},
+		{"application/json", "Application/JSON", true},
+		{"application/json", "application/*", true},
+		{"application/json", "*/*", true},
+		{"application/json", "text/*", false},
+		{"application/json", "application/xml", false},
+		{"application/json;v=2", "application/json", true},
+		{"application/json;v=2", "application/json;v=2", true},
+		{"application/json;v=2", "application/json;v=3", false},
+		{"application/json", "application/json;v=2", false},
+		{"text/plain;charset=utf-8", "text/plain;charset=UTF-8", true},
+	}
+
+	for _, tt := range matchTests {
+		offer, _ := ParseAccept(tt.offer)
+		mr, _ := ParseAccept(tt.mediaRange)
+		assert.Equal(t, tt.expected, offerMatches(offer[0], mr[0]), tt.offer+" within "+tt.mediaRange)
+	}
+}
+
+func TestShouldMatchWildcardsAgainstDeclaredMediaTypes(t *testing.T) {
+	var acceptTests = []struct {
+		acceptheader string
+		expected     string
+	}{
+		{"application/*", "application/json"},
+		{"text/*", "text/csv"},
+		{"text/*;q=0.5, application/xml", "application/xml"},
+		{"*/*", "text/csv"},
+		{"application/atom+xml", "application/xml"},
+	}
+
+	negotiator := New(NewCSV(), NewJSON(), NewXML())
+
+	for _, tt := range acceptTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", tt.acceptheader)
+		recorder := httptest.NewRecorder()
+
+		negotiator.Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, tt.expected, recorder.HeaderMap.Get("Content-Type"), tt.acceptheader)
+	}
+}
+
+func TestShouldMatchParametersAgainstDeclaredMediaTypes(t *testing.T) {
+	v1 := &fakeOfferProcessor{[]string{"application/vnd.example+json;version=1"}, "v1"}
+	v2 := &fakeOfferProcessor{[]string{"application/vnd.example+json;version=2"}, "v2"}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode} {
+		negotiator := New(v1, v2).WithMode(mode)
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", "application/vnd.example+json;version=2")
+		recorder := httptest.NewRecorder()
+
+		negotiator.Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, "v2", recorder.Body.String())
+	}
+}
+
+func TestShouldFallBackToCanProcessForUndeclaredMediaTypes(t *testing.T) {
+	negotiator := New(NewJSON(), &fakeProcessor{})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/negotiatortesting")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "boo ya!", recorder.Body.String())
+}
+
+func TestShouldTreatProcessorWithoutValidMediaTypesAsUndeclared(t *testing.T) {
+	negotiator := New(NewJSON().(ContentTypeSettable).SetContentType("json"))
+
+	for _, accept := range []string{"", "*/*"} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if accept != "" {
+			req.Header.Add("Accept", accept)
+		}
+		recorder := httptest.NewRecorder()
+
+		err := negotiator.Negotiate(recorder, req, "foo")
+
+		assert.NoError(t, err, accept)
+		assert.Equal(t, http.StatusOK, recorder.Code, accept)
+		assert.Equal(t, "json", recorder.HeaderMap.Get("Content-Type"), accept)
+	}
+}
+
+type fakeOfferProcessor struct {
+	mediaTypes []string
+	body       string
+}
+
+func (*fakeOfferProcessor) CanProcess(mediaRange string) bool {
+	return false
+}
+
+func (p *fakeOfferProcessor) MediaTypes() []string {
+	return p.mediaTypes
+}
+
+func (p *fakeOfferProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	w.Header().Set("Content-Type", p.mediaTypes[0])
+	w.Write([]byte(p.body))
+	return nil
+}
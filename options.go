@@ -0,0 +1,219 @@
+package negotiator
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option configures a Negotiator built by NewNegotiator or With. Each configuration
+// method of Negotiator has an Option of the same name.
+type Option func(n *Negotiator)
+
+// DecisionHook is called with every decision made by Negotiate or Respond, before the
+// response is written, for instance for logging or metrics.
+type DecisionHook func(req *http.Request, d Decision)
+
+// ErrorHook is called when Negotiate or Respond fails, with the *NotAcceptableError or
+// the error of the response processor.
+type ErrorHook func(req *http.Request, err error)
+
+// NewNegotiator builds a Negotiator from options, applied in order:
+//
+//	n := negotiator.NewNegotiator(
+//	    negotiator.WithProcessors(negotiator.NewJSON(), negotiator.NewXML()),
+//	    negotiator.WithDefaultProcessor(negotiator.NewJSON()),
+//	    negotiator.WithFallback(negotiator.FallbackDefault),
+//	    negotiator.WithBuffering(true),
+//	)
+func NewNegotiator(options ...Option) *Negotiator {
+	return (&Negotiator{parseLimits: DefaultParseLimits}).With(options...)
+}
+
+// With applies the options to a copy of the Negotiator. A new Negotiator is returned and
+// the original is unchanged.
+func (n *Negotiator) With(options ...Option) *Negotiator {
+	n2 := *n
+	for _, option := range options {
+		option(&n2)
+	}
+	n2.variants = processorVariants(n2.processors, n2.qualities)
+	n2.decodeChain = n2.decoders
+	if !n2.noBuiltIns {
+		n2.decodeChain = append(n2.decoders[:len(n2.decoders):len(n2.decoders)], defaultDecoders...)
+	}
+	n2.cache = nil
+	if n2.cacheSize > 0 {
+		n2.cache = newDecisionCache(n2.cacheSize)
+	}
+	return &n2
+}
+
+// WithProcessors adds response processors, with a server quality of 1 (see
+// Negotiator.Add).
+func WithProcessors(responseProcessors ...ResponseProcessor) Option {
+	return WithServerQuality(1.0, responseProcessors...)
+}
+
+// WithServerQuality adds response processors with the server quality qs (see
+// Negotiator.AddWithQuality).
+func WithServerQuality(qs float64, responseProcessors ...ResponseProcessor) Option {
+	return func(n *Negotiator) {
+		n.processors = append(append([]ResponseProcessor(nil), n.processors...), responseProcessors...)
+		n.qualities = append(append([]float64(nil), n.qualities...), uniformQualities(len(responseProcessors), qs)...)
+	}
+}
+
+// WithMode chooses the algorithm used to select a response processor (see
+// Negotiator.WithMode).
+func WithMode(mode Mode) Option {
+	return func(n *Negotiator) { n.mode = mode }
+}
+
+// WithAjax turns the special handling of Ajax requests on or off (see
+// Negotiator.WithAjax).
+func WithAjax(enabled bool) Option {
+	return func(n *Negotiator) { n.noAjax = !enabled }
+}
+
+// WithNotAcceptableHandler installs the handler that writes 406 responses (see
+// Negotiator.WithNotAcceptableHandler).
+func WithNotAcceptableHandler(handler NotAcceptableHandler) Option {
+	return func(n *Negotiator) { n.notAcceptable = handler }
+}
+
+// WithFallback sets the policy for requests that cannot be satisfied (see
+// Negotiator.WithFallback).
+func WithFallback(policy FallbackPolicy) Option {
+	return func(n *Negotiator) { n.fallback = policy }
+}
+
+// WithDefaultProcessor sets the processor used by FallbackDefault (see
+// Negotiator.WithDefaultProcessor).
+func WithDefaultProcessor(processor ResponseProcessor) Option {
+	return func(n *Negotiator) { n.defaultProcessor = processor }
+}
+
+// WithVary adds request headers to the Vary header of every response (see
+// Negotiator.WithVary).
+func WithVary(headers ...string) Option {
+	return func(n *Negotiator) { n.vary = append(append([]string(nil), n.vary...), headers...) }
+}
+
+// WithTrace turns tracing on or off (see Negotiator.WithTrace).
+func WithTrace(enabled bool) Option {
+	return func(n *Negotiator) { n.trace = enabled }
+}
+
+// WithLanguages sets the languages in which responses are available (see
+// Negotiator.WithLanguages).
+func WithLanguages(tags ...string) Option {
+	return func(n *Negotiator) { n.languages = append([]string(nil), tags...) }
+}
+
+// WithCharsets sets the charsets in which responses are available (see
+// Negotiator.WithCharsets).
+func WithCharsets(charsets ...string) Option {
+	return func(n *Negotiator) {
+		n.charsets = nil
+		for _, charset := range charsets {
+			if charset = strings.ToLower(charset); encoderFor(charset) != nil {
+				n.charsets = append(n.charsets, charset)
+			}
+		}
+	}
+}
+
+// WithByteOrderMark chooses whether transcoded responses begin with a byte order mark
+// (see Negotiator.WithByteOrderMark).
+func WithByteOrderMark(enabled bool) Option {
+	return func(n *Negotiator) { n.bom = enabled }
+}
+
+// WithCompression turns on compression of responses (see Negotiator.WithCompression).
+func WithCompression(minSize int, encodings ...string) Option {
+	if len(encodings) == 0 {
+		encodings = []string{EncodingGzip, EncodingDeflate}
+	}
+
+	return func(n *Negotiator) {
+		n.compressMinSize = minSize
+		n.encodings = nil
+		for _, encoding := range encodings {
+			if encoding = strings.ToLower(encoding); encoding == EncodingGzip || encoding == EncodingDeflate {
+				n.encodings = append(n.encodings, encoding)
+			}
+		}
+	}
+}
+
+// WithBuffering turns buffered rendering on or off (see Negotiator.WithBuffering).
+func WithBuffering(enabled bool) Option {
+	return func(n *Negotiator) { n.buffered = enabled }
+}
+
+// WithDecoders adds request processors (see Negotiator.AddDecoders).
+func WithDecoders(requestProcessors ...RequestProcessor) Option {
+	return func(n *Negotiator) {
+		n.decoders = append(append([]RequestProcessor(nil), n.decoders...), requestProcessors...)
+	}
+}
+
+// WithBuiltInDecoders turns the built-in request processors on or off (see
+// Negotiator.WithBuiltInDecoders).
+func WithBuiltInDecoders(enabled bool) Option {
+	return func(n *Negotiator) { n.noBuiltIns = !enabled }
+}
+
+// WithDecodeOptions sets the safeguards applied by Decode (see
+// Negotiator.WithDecodeOptions).
+func WithDecodeOptions(options DecodeOptions) Option {
+	return func(n *Negotiator) { n.decodeOptions = options }
+}
+
+// WithParseLimits sets the limits on parsing Accept headers and the policy for headers
+// that exceed them (see Negotiator.WithParseLimits).
+func WithParseLimits(limits ParseLimits, policy OverflowPolicy) Option {
+	return func(n *Negotiator) { n.parseLimits, n.overflow = limits, policy }
+}
+
+// WithDecisionCache keeps the decisions made for recent requests (see
+// Negotiator.WithDecisionCache).
+func WithDecisionCache(size int) Option {
+	return func(n *Negotiator) { n.cacheSize = size }
+}
+
+// WithDecisionHook sets the hook called with every decision (see
+// Negotiator.WithDecisionHook).
+func WithDecisionHook(hook DecisionHook) Option {
+	return func(n *Negotiator) { n.onDecision = hook }
+}
+
+// WithErrorHook sets the hook called when negotiation or rendering fails (see
+// Negotiator.WithErrorHook).
+func WithErrorHook(hook ErrorHook) Option {
+	return func(n *Negotiator) { n.onError = hook }
+}
+
+// WithAjax turns the special handling of Ajax requests on or off. It is on by default:
+// Ajax requests are then answered by the first processor that implements
+// AjaxResponseProcessor, and X-Requested-With is added to Vary. When it is off, Ajax
+// requests are negotiated like any other. A new Negotiator is returned with the same
+// processors as the original.
+func (n *Negotiator) WithAjax(enabled bool) *Negotiator {
+	return n.With(WithAjax(enabled))
+}
+
+// WithDecisionHook sets a hook that is called with every decision made by Negotiate or
+// Respond, or removes it if the hook is nil. While a hook is installed, every Decision
+// records its Reasons, as it does while tracing is on. A new Negotiator is returned
+// with the same processors as the original.
+func (n *Negotiator) WithDecisionHook(hook DecisionHook) *Negotiator {
+	return n.With(WithDecisionHook(hook))
+}
+
+// WithErrorHook sets a hook that is called when Negotiate or Respond fails, or removes it
+// if the hook is nil. A new Negotiator is returned with the same processors as the
+// original.
+func (n *Negotiator) WithErrorHook(hook ErrorHook) *Negotiator {
+	return n.With(WithErrorHook(hook))
+}
@@ -0,0 +1,137 @@
+package negotiator
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNegotiatorShouldApplyOptions(t *testing.T) {
+	json, xml, txt := NewJSON(), NewXML(), NewTXT()
+	handler := func(w http.ResponseWriter, req *http.Request, err *NotAcceptableError) {}
+
+	n := NewNegotiator(
+		WithProcessors(json),
+		WithServerQuality(0.5, xml),
+		WithMode(RFC9110Mode),
+		WithAjax(false),
+		WithNotAcceptableHandler(handler),
+		WithFallback(FallbackDefault),
+		WithDefaultProcessor(txt),
+		WithVary("Cookie"),
+		WithTrace(true),
+		WithLanguages("en"),
+		WithCharsets("UTF-8", "bogus"),
+		WithByteOrderMark(true),
+		WithCompression(100, "GZIP"),
+		WithBuffering(true),
+		WithDecoders(NewJSONDecoder()),
+		WithDecodeOptions(DecodeOptions{MaxBodySize: 10}),
+	)
+
+	assert.Equal(t, []ResponseProcessor{json, xml}, n.processors)
+	assert.Equal(t, []float64{1, 0.5}, n.qualities)
+	assert.Equal(t, RFC9110Mode, n.mode)
+	assert.True(t, n.noAjax)
+	assert.NotNil(t, n.notAcceptable)
+	assert.Equal(t, FallbackDefault, n.fallback)
+	assert.Equal(t, txt, n.defaultProcessor)
+	assert.Equal(t, []string{"Cookie"}, n.vary)
+	assert.True(t, n.trace)
+	assert.Equal(t, []string{"en"}, n.languages)
+	assert.Equal(t, []string{CharsetUTF8}, n.charsets)
+	assert.True(t, n.bom)
+	assert.Equal(t, 100, n.compressMinSize)
+	assert.Equal(t, []string{EncodingGzip}, n.encodings)
+	assert.True(t, n.buffered)
+	assert.Len(t, n.decoders, 1)
+	assert.Equal(t, int64(10), n.decodeOptions.MaxBodySize)
+}
+
+func TestConstructorsShouldAgreeWithOptions(t *testing.T) {
+	json, xml := NewJSON(), NewXML()
+
+	assert.Equal(t, NewNegotiator(WithProcessors(json, xml)), New(json, xml))
+	assert.Equal(t, NewNegotiator(WithProcessors(json), WithServerQuality(0.8, xml)), New(json).AddWithQuality(0.8, xml))
+	assert.Equal(t, NewNegotiator(WithMode(UnifiedMode), WithVary("Cookie")), New().WithMode(UnifiedMode).WithVary("Cookie"))
+}
+
+func TestServerQualityShouldBeValid(t *testing.T) {
+	var qualityTests = []struct {
+		qs       float64
+		expected float64
+	}{
+		{0.5, 0.5},
+		{0, 0},
+		{-0.5, 0},
+		{1.5, 1},
+		{math.Inf(1), 1},
+		{math.Inf(-1), 0},
+		{math.NaN(), 1},
+	}
+
+	for _, tt := range qualityTests {
+		n := New().AddWithQuality(tt.qs, NewJSON()).WithMode(RFC9110Mode)
+
+		d, _ := n.Decide(newAcceptRequest("application/json"))
+
+		assert.Equal(t, []float64{tt.expected}, n.qualities, "%g", tt.qs)
+		assert.Equal(t, tt.expected, d.Quality, "%g", tt.qs)
+	}
+}
+
+func TestWithShouldNotModifyOriginal(t *testing.T) {
+	original := New(NewJSON()).WithVary("Cookie")
+	changed := original.With(WithVary("Origin"), WithProcessors(NewXML()), WithBuffering(true))
+
+	assert.Equal(t, []string{"Cookie"}, original.vary)
+	assert.Len(t, original.processors, 1)
+	assert.False(t, original.buffered)
+	assert.Equal(t, []string{"Cookie", "Origin"}, changed.vary)
+	assert.Len(t, changed.processors, 2)
+}
+
+func TestWithAjaxShouldDisableAjaxResponder(t *testing.T) {
+	n := New(NewJSON(), NewXML()).WithAjax(false)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	req.Header.Set(xRequestedWith, xmlHttpRequest)
+	recorder := httptest.NewRecorder()
+
+	n.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "application/xml", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "Accept", recorder.HeaderMap.Get("Vary"))
+}
+
+func TestHooksShouldBeCalled(t *testing.T) {
+	var decisions []Decision
+	var errs []error
+	n := NewNegotiator(
+		WithProcessors(NewJSON()),
+		WithDecisionHook(func(req *http.Request, d Decision) { decisions = append(decisions, d) }),
+		WithErrorHook(func(req *http.Request, err error) { errs = append(errs, err) }),
+	)
+
+	var acceptTests = []string{"application/json", "image/png"}
+	for _, accept := range acceptTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		n.Negotiate(httptest.NewRecorder(), req, "foo")
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	n.Negotiate(httptest.NewRecorder(), req, math.Inf(1))
+
+	assert.Len(t, decisions, 3)
+	assert.Equal(t, 0, decisions[0].Index)
+	assert.Nil(t, decisions[1].Processor)
+	assert.Len(t, errs, 2)
+	assert.True(t, errors.Is(errs[0], ErrNotAcceptable))
+	assert.EqualError(t, errs[1], "json: unsupported value: +Inf")
+}
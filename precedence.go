@@ -0,0 +1,155 @@
+package negotiator
+
+// Mode selects the algorithm a Negotiator uses to choose a response processor.
+//
+// In every mode, the client's quality is multiplied by the server quality (qs) of
+// the processor and of its declared media type, so that when several processors are
+// equally acceptable to the client the server's preference decides.
+type Mode int
+
+const (
+	// LegacyMode orders the media ranges by weight, giving ranges without an
+	// explicit q value a default weight according to their precedence (see
+	// ParameteredMediaRangeWeight and friends), and picks the first processor that
+	// can handle the heaviest range. "*/*" selects the first processor, all else
+	// being equal. This is the default.
+	LegacyMode Mode = iota
+
+	// RFC9110Mode follows RFC 9110 section 12.5.1. Each processor is given the
+	// quality of the most specific media range that matches it; q values default to
+	// 1 and a processor whose most specific match has q=0 is never chosen. The
+	// processor with the highest quality wins. Ties are broken by the order of the
+	// matching media ranges in the Accept header, then by processor order.
+	RFC9110Mode
+
+	// UnifiedMode chooses the media type, language, charset and content coding
+	// together, in the spirit of Apache's mod_negotiation. Every combination that the
+	// Negotiator can produce is scored by the product of the client's quality for each
+	// dimension and the server quality, so that a representation that is best on media
+	// type but unacceptable on language loses to one that is acceptable on both.
+	// Media types are matched as in RFC9110Mode. The breakdown of the winning score is
+	// given in the Decision (see Score).
+	UnifiedMode
+)
+
+// anyMediaRange stands in for an absent Accept header.
+var anyMediaRange = []MediaRange{{Type: "*", Subtype: "*", Q: 1.0}}
+
+// scoredVariant is a variant together with the media range that selects it and its
+// quality.
+type scoredVariant struct {
+	variant
+	// rangeIndex is the position of the selecting media range.
+	rangeIndex int
+	// q is the client's quality for the variant, and score the product of q and qs.
+	q, score float64
+	// entry is the position in the trace of the decisive comparison, or -1 without a
+	// trace.
+	entry int
+}
+
+// selectVariant scores every variant against the media ranges and returns the best,
+// or false if none is acceptable. In LegacyMode the ranges must already be in
+// weight order. If trace is not nil, every comparison is recorded in it.
+func selectVariant(vs []variant, ranges []MediaRange, mode Mode, trace *[]TraceEntry) (scoredVariant, bool) {
+	var chosen scoredVariant
+	found := false
+
+	var buf [16]scoredVariant
+	for _, sv := range scoreVariants(buf[:0], vs, ranges, mode, trace) {
+		if !found || sv.score > chosen.score || (sv.score == chosen.score && sv.rangeIndex < chosen.rangeIndex) {
+			chosen = sv
+			found = true
+		}
+	}
+
+	if found && trace != nil {
+		(*trace)[chosen.entry].Chosen = true
+	}
+
+	return chosen, found
+}
+
+// scoreVariants scores every variant against the media ranges and appends those that
+// are acceptable to dst, in order. If trace is not nil, every comparison is recorded in
+// it.
+func scoreVariants(dst []scoredVariant, vs []variant, ranges []MediaRange, mode Mode, trace *[]TraceEntry) []scoredVariant {
+	scored := dst
+
+	for _, v := range vs {
+		var r int
+		var q float64
+
+		if mode == LegacyMode {
+			r = firstMatch(v, ranges)
+		} else {
+			r = mostSpecificMatch(v, ranges)
+		}
+
+		entry := -1
+		if trace != nil {
+			traceVariant(trace, v, ranges, r, mode)
+			entry = len(*trace) - len(ranges) + r
+		}
+
+		if r < 0 {
+			continue
+		}
+
+		if mode == LegacyMode {
+			q = legacyWeight(ranges[r])
+		} else {
+			if ranges[r].Q == 0 || v.qs == 0 {
+				continue
+			}
+			q = ranges[r].Q
+		}
+
+		scored = append(scored, scoredVariant{v, r, q, q * v.qs, entry})
+	}
+
+	return scored
+}
+
+// firstMatch returns the index of the first media range that selects the variant,
+// or -1 if there is none.
+func firstMatch(v variant, ranges []MediaRange) int {
+	for j, mr := range ranges {
+		if v.matches(mr) {
+			return j
+		}
+	}
+	return -1
+}
+
+// mostSpecificMatch returns the index of the most specific media range that selects
+// the variant, or -1 if there is none. Where ranges are equally specific, the
+// earliest wins.
+func mostSpecificMatch(v variant, ranges []MediaRange) int {
+	best, bestSpecificity := -1, -1
+
+	for j, mr := range ranges {
+		if !v.matches(mr) {
+			continue
+		}
+
+		if s := specificity(mr); s > bestSpecificity {
+			best, bestSpecificity = j, s
+		}
+	}
+
+	return best
+}
+
+// specificity ranks media ranges so that more specific ranges override less
+// specific ones: "*/*" < "type/*" < "type/subtype" < "type/subtype;param=value".
+func specificity(mr MediaRange) int {
+	switch {
+	case mr.Type == "*":
+		return 0
+	case mr.Subtype == "*":
+		return 1
+	default:
+		return 2 + mr.paramCount()
+	}
+}
@@ -0,0 +1,121 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRFC9110ModeSelection(t *testing.T) {
+	var selectionTests = []struct {
+		acceptheader string
+		expected     string
+	}{
+		// ties are broken by the client's order, not by the processor order
+		{"text/plain, application/json", "text/plain"},
+		{"application/json, text/plain", "application/json"},
+		{"text/plain;q=0.5, application/json;q=0.5", "text/plain"},
+		// explicit q values win, without any implicit weights
+		{"text/plain;q=0.5, application/json;q=0.6", "application/json"},
+		{"*/*, text/plain;q=0.9", "application/json"},
+		// q=0 excludes, even when a wildcard would otherwise match
+		{"application/json;q=0, */*", "application/xml"},
+		{"*/*;q=0, text/plain", "text/plain"},
+		{"application/json;q=0, application/xml;q=0, text/plain;q=0, */*", ""},
+		// the most specific range decides the quality
+		{"text/*;q=0.1, text/plain, application/json;q=0.5", "text/plain"},
+		{"text/*, text/plain;q=0.1, application/json;q=0.5", "application/json"},
+	}
+
+	negotiator := New(NewJSON(), NewXML(), NewTXT()).WithMode(RFC9110Mode)
+
+	for _, tt := range selectionTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", tt.acceptheader)
+		recorder := httptest.NewRecorder()
+
+		negotiator.Negotiate(recorder, req, "foo")
+
+		if tt.expected == "" {
+			assert.Equal(t, http.StatusNotAcceptable, recorder.Code, tt.acceptheader)
+		} else {
+			assert.Equal(t, tt.expected, recorder.HeaderMap.Get("Content-Type"), tt.acceptheader)
+		}
+	}
+}
+
+func TestRFC9110ModeShouldUseFirstProcessorIfNoAcceptHeader(t *testing.T) {
+	negotiator := New(NewTXT(), NewJSON()).WithMode(RFC9110Mode)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "text/plain", recorder.HeaderMap.Get("Content-Type"))
+}
+
+func TestLegacyModeIsTheDefault(t *testing.T) {
+	negotiator := New(NewJSON(), NewTXT())
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/json;q=0, text/plain;q=0")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"))
+}
+
+func TestSpecificity(t *testing.T) {
+	ranges, _ := ParseAccept("*/*, text/*, text/html, text/html;level=1")
+
+	for i := 1; i < len(ranges); i++ {
+		assert.True(t, specificity(ranges[i-1]) < specificity(ranges[i]), ranges[i].String())
+	}
+}
+
+func TestServerQualityShouldBreakClientTies(t *testing.T) {
+	var qualityTests = []struct {
+		acceptheader string
+		expected     string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml;q=0.9, application/json;q=0.9", "application/json"},
+		{"application/xml, application/json;q=0.5", "application/xml"},
+	}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode} {
+		negotiator := New().AddWithQuality(0.8, NewXML()).AddWithQuality(1.0, NewJSON()).WithMode(mode)
+
+		for _, tt := range qualityTests {
+			req, _ := http.NewRequest("GET", "/", nil)
+			if tt.acceptheader != "" {
+				req.Header.Add("Accept", tt.acceptheader)
+			}
+			recorder := httptest.NewRecorder()
+
+			negotiator.Negotiate(recorder, req, "foo")
+
+			assert.Equal(t, tt.expected, recorder.HeaderMap.Get("Content-Type"), tt.acceptheader)
+		}
+	}
+}
+
+func TestServerQualityOfDeclaredMediaTypes(t *testing.T) {
+	xml := &fakeOfferProcessor{[]string{"application/xml;qs=0.5"}, "xml"}
+	json := &fakeOfferProcessor{[]string{"application/json;qs=0.9"}, "json"}
+	negotiator := New(xml, json).WithMode(RFC9110Mode)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/xml, application/json;q=0.6")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	// 0.6 * 0.9 beats 1.0 * 0.5
+	assert.Equal(t, "json", recorder.Body.String())
+}
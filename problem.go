@@ -0,0 +1,306 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// problemXMLNamespace is the XML namespace of problem details, from RFC 9457 appendix B.
+const problemXMLNamespace = "urn:ietf:rfc:7807"
+
+// ProblemDetails describes an error in the form of RFC 9457.
+type ProblemDetails struct {
+	// Type is a URI reference that identifies the problem type. If empty, it is taken to
+	// be "about:blank" and omitted.
+	Type string
+	// Title is a short summary of the problem type. If empty, the text of the status is
+	// used.
+	Title string
+	// Status is the HTTP status code. If zero, 500 is used.
+	Status int
+	// Detail explains this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies this occurrence of the problem.
+	Instance string
+	// Extensions are additional members, rendered alongside the others. Members that
+	// would replace one of the above are ignored.
+	Extensions map[string]interface{}
+}
+
+// ProblemDetailer interface allows an error to describe itself as problem details, for
+// Negotiator.Error.
+type ProblemDetailer interface {
+	ProblemDetails() ProblemDetails
+}
+
+// problemFormats are the formats in which problem details are rendered; the first is
+// used if the client accepts none of them.
+var problemFormats = []MediaRange{
+	{Type: "application", Subtype: "problem+json", Q: 1.0},
+	{Type: "application", Subtype: "problem+xml", Q: 1.0},
+	{Type: "application", Subtype: "json", Q: 1.0},
+	{Type: "application", Subtype: "xml", Q: 1.0},
+	{Type: "text", Subtype: "plain", Q: 1.0},
+}
+
+// Error responds with problem details (RFC 9457) describing err, as
+// application/problem+json, application/problem+xml or plain text, whichever the client
+// prefers; a client that accepts only application/json or application/xml is given the
+// same documents under those media types. If the client accepts none of these, JSON is
+// used all the same.
+//
+// If err, or an error it wraps, implements ProblemDetailer, the problem details are its
+// own; *DecodeError and *UnsupportedMediaTypeError do. Otherwise an error with a
+// StatusCode() int method gives the status and its message the detail, and any other
+// error is reported as 500 (Internal Server Error) without detail, so as not to reveal
+// internals.
+//
+// The problem details are sent as any negotiated response is: with the Negotiator's
+// Vary headers, transcoded and compressed as Accept-Charset and Accept-Encoding allow,
+// buffered if buffering is on, and with the error hook called if sending them fails. An
+// unacceptable charset or content coding does not turn the error into a 406: the
+// document is sent as it is. If the extensions cannot be marshalled, the problem
+// details are sent without them and the marshalling error is returned.
+func (n *Negotiator) Error(w http.ResponseWriter, req *http.Request, err error) error {
+	problem := problemDetailsOf(err)
+
+	ranges := anyMediaRange
+	if accept := combinedHeader(req.Header, "Accept"); accept != "" {
+		if parsed, exceeded := n.parseAccept(nil, accept, true); !exceeded || n.overflow == OverflowTruncate {
+			ranges = parsed
+		}
+	}
+
+	format := problemFormats[0]
+	if chosen, ok := selectVariant(offerVariants(problemFormats), ranges, RFC9110Mode, nil); ok {
+		format = problemFormats[chosen.index]
+	}
+
+	d := Decision{Index: -1, MediaType: format.MediaType(), explain: n.explains()}
+	d.Vary = append(d.Vary, "Accept")
+	n.negotiateCharset(req, &d)
+	n.negotiateEncoding(req, &d)
+
+	contentType, body, merr := problem.marshal(format, d.Charset)
+	if merr != nil {
+		// The standard members always marshal, so without the extensions the problem
+		// details can still be sent.
+		problem.Extensions = nil
+		contentType, body, _ = problem.marshal(format, d.Charset)
+	}
+	d.Processor = problemProcessor{contentType}
+	d.Vary = append(d.Vary, n.vary...)
+	addVary(w.Header(), d.Vary...)
+
+	rerr := n.render(w, req, d, ResponseOptions{Status: problem.Status}, body)
+	if rerr != nil && n.onError != nil {
+		n.onError(req, rerr)
+	}
+	if rerr != nil {
+		return rerr
+	}
+	return merr
+}
+
+// problemProcessor writes a problem details document, given as the model, that has
+// already been marshalled.
+type problemProcessor struct {
+	contentType string
+}
+
+func (p problemProcessor) CanProcess(string) bool {
+	return false
+}
+
+func (p problemProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	w.Header().Set("Content-Type", p.contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, err := w.Write(model.([]byte))
+	return err
+}
+
+// problemDetailsOf converts an error into problem details, filling in the status and
+// title if they are missing.
+func problemDetailsOf(err error) ProblemDetails {
+	var problem ProblemDetails
+
+	var pd ProblemDetailer
+	var sc statusCoder
+	switch {
+	case errors.As(err, &pd):
+		problem = pd.ProblemDetails()
+	case errors.As(err, &sc):
+		problem = ProblemDetails{Status: sc.StatusCode(), Detail: err.Error()}
+	}
+
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	return problem
+}
+
+// marshal renders the problem details in a format, returning the content type and the
+// document. The charset, if any, is the one the document will be transcoded into.
+func (p ProblemDetails) marshal(format MediaRange, charset string) (string, []byte, error) {
+	switch format.Subtype {
+	case "problem+json", "json":
+		body, err := p.marshalJSON()
+		return format.MediaType(), body, err
+	case "problem+xml", "xml":
+		body, err := p.marshalXML(charset)
+		return format.MediaType(), body, err
+	default:
+		return "text/plain; charset=utf-8", p.marshalText(), nil
+	}
+}
+
+// members returns the standard members that are set, in the order of RFC 9457, followed
+// by the names of the extensions in alphabetical order.
+func (p ProblemDetails) members() ([]string, map[string]interface{}) {
+	values := map[string]interface{}{"title": p.Title, "status": p.Status}
+	names := []string{}
+	if p.Type != "" {
+		names = append(names, "type")
+		values["type"] = p.Type
+	}
+	names = append(names, "title", "status")
+	if p.Detail != "" {
+		names = append(names, "detail")
+		values["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		names = append(names, "instance")
+		values["instance"] = p.Instance
+	}
+
+	var extensions []string
+	for name, value := range p.Extensions {
+		switch name {
+		case "type", "title", "status", "detail", "instance":
+			continue
+		}
+		extensions = append(extensions, name)
+		values[name] = value
+	}
+	sort.Strings(extensions)
+
+	return append(names, extensions...), values
+}
+
+func (p ProblemDetails) marshalJSON() ([]byte, error) {
+	names, values := p.members()
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, name := range names {
+		value, err := json.Marshal(values[name])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(name) + ":")
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// marshalXML follows RFC 9457 appendix B: each member becomes an element, and the items
+// of an array become "i" elements. The XML declaration names the charset into which the
+// document will be transcoded, or UTF-8 if none.
+func (p ProblemDetails) marshalXML(charset string) ([]byte, error) {
+	names, values := p.members()
+	if charset == "" {
+		charset = "UTF-8"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<?xml version=\"1.0\" encoding=\"%s\"?>\n", charset)
+	enc := xml.NewEncoder(&b)
+	problem := xml.StartElement{Name: xml.Name{Space: problemXMLNamespace, Local: "problem"}}
+	if err := enc.EncodeToken(problem); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if err := encodeProblemMember(enc, name, values[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.EncodeToken(problem.End()); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func encodeProblemMember(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	v := reflect.ValueOf(value)
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeProblemMember(enc, "i", v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	return enc.EncodeElement(value, start)
+}
+
+func (p ProblemDetails) marshalText() []byte {
+	names, values := p.members()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d %s\n", p.Status, p.Title)
+	if p.Detail != "" {
+		b.WriteString(p.Detail + "\n")
+	}
+	for _, name := range names {
+		switch name {
+		case "title", "status", "detail":
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %v\n", name, values[name])
+	}
+	return b.Bytes()
+}
+
+// ProblemDetails describes the error as problem details.
+func (e *DecodeError) ProblemDetails() ProblemDetails {
+	extensions := map[string]interface{}{"kind": e.Kind}
+	if e.Field != "" {
+		extensions["field"] = e.Field
+	}
+	return ProblemDetails{Status: e.Status, Detail: e.Message, Extensions: extensions}
+}
+
+// ProblemDetails describes the error as problem details.
+func (e *UnsupportedMediaTypeError) ProblemDetails() ProblemDetails {
+	return ProblemDetails{
+		Status:     http.StatusUnsupportedMediaType,
+		Detail:     fmt.Sprintf("Content type %q is not supported.", e.ContentType),
+		Extensions: map[string]interface{}{"supported": e.Supported},
+	}
+}
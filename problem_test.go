@@ -0,0 +1,229 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type outOfCreditError struct {
+	balance  int
+	accounts []string
+}
+
+func (e *outOfCreditError) Error() string {
+	return "out of credit"
+}
+
+func (e *outOfCreditError) ProblemDetails() ProblemDetails {
+	return ProblemDetails{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   http.StatusForbidden,
+		Detail:   fmt.Sprintf("Your current balance is %d.", e.balance),
+		Instance: "/account/12345/msgs/abc",
+		Extensions: map[string]interface{}{
+			"balance":  e.balance,
+			"accounts": e.accounts,
+			"status":   999,
+		},
+	}
+}
+
+func newProblemRequest(accept string) *http.Request {
+	req, _ := http.NewRequest("GET", "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return req
+}
+
+func TestErrorShouldRenderProblemJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := fmt.Errorf("buying: %w", &outOfCreditError{30, []string{"/account/12345", "/account/67890"}})
+
+	New().Error(recorder, newProblemRequest("application/json, text/plain;q=0.5"), err)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "application/json", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "Accept", recorder.HeaderMap.Get("Vary"))
+	assert.Equal(t, `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",`+
+		`"status":403,"detail":"Your current balance is 30.","instance":"/account/12345/msgs/abc",`+
+		`"accounts":["/account/12345","/account/67890"],"balance":30}`+"\n", recorder.Body.String())
+}
+
+func TestErrorShouldRenderProblemXML(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	New().Error(recorder, newProblemRequest("application/problem+xml"), &outOfCreditError{30, []string{"/account/12345"}})
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "application/problem+xml", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type>`+
+		`<title>You do not have enough credit.</title><status>403</status>`+
+		`<detail>Your current balance is 30.</detail><instance>/account/12345/msgs/abc</instance>`+
+		`<accounts><i>/account/12345</i></accounts><balance>30</balance></problem>`+"\n", recorder.Body.String())
+}
+
+func TestErrorShouldRenderText(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	New().Error(recorder, newProblemRequest("text/*"), &outOfCreditError{30, nil})
+
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "403 You do not have enough credit.\nYour current balance is 30.\n"+
+		"type: https://example.com/probs/out-of-credit\ninstance: /account/12345/msgs/abc\n"+
+		"accounts: []\nbalance: 30\n", recorder.Body.String())
+}
+
+func TestErrorShouldChooseFormat(t *testing.T) {
+	var formatTests = []struct {
+		accept   string
+		expected string
+	}{
+		{"", "application/problem+json"},
+		{"*/*", "application/problem+json"},
+		{"application/xml", "application/xml"},
+		{"application/*;q=0.5, application/problem+xml", "application/problem+xml"},
+		{"image/png", "application/problem+json"},
+	}
+
+	for _, tt := range formatTests {
+		recorder := httptest.NewRecorder()
+		New().Error(recorder, newProblemRequest(tt.accept), errors.New("boom"))
+		assert.Equal(t, tt.expected, recorder.HeaderMap.Get("Content-Type"), tt.accept)
+	}
+}
+
+func TestErrorShouldHideDetailOfPlainErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	New().Error(recorder, newProblemRequest("application/problem+json"), errors.New("database password is hunter2"))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, `{"title":"Internal Server Error","status":500}`+"\n", recorder.Body.String())
+}
+
+func TestErrorShouldDescribeDecodingErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := New().WithBuiltInDecoders(false).AddDecoders(NewJSONDecoder(), NewXMLDecoder()).Decode(newBodyRequest("text/csv", "a,b"), &decodeUser{})
+
+	New().Error(recorder, newProblemRequest(""), err)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+	assert.Equal(t, `{"title":"Unsupported Media Type","status":415,"detail":"Content type \"text/csv\" is not supported.",`+
+		`"supported":["application/json","application/xml"]}`+"\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	New().Error(recorder, newProblemRequest(""), unknownFieldError("email"))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, `{"title":"Bad Request","status":400,"detail":"unknown field \"email\"","field":"email","kind":"unknown-field"}`+"\n",
+		recorder.Body.String())
+}
+
+func TestErrorShouldBeSentLikeNegotiatedResponses(t *testing.T) {
+	negotiator := New().WithVary("Cookie").WithCharsets(CharsetUTF16LE).WithCompression(0)
+
+	req := newProblemRequest("text/plain")
+	req.Header.Set("Accept-Charset", "utf-16le")
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Error(recorder, req, &outOfCreditError{30, nil})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "text/plain; charset=utf-16le", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "nosniff", recorder.HeaderMap.Get("X-Content-Type-Options"))
+	assert.Equal(t, EncodingGzip, recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, "Accept, Accept-Charset, Accept-Encoding, Cookie", recorder.HeaderMap.Get("Vary"))
+	assert.Equal(t, "4\x000\x003\x00 \x00", decompress(t, EncodingGzip, recorder.Body)[:8])
+}
+
+func TestErrorShouldSendJSONAsItIsWhateverTheCharset(t *testing.T) {
+	negotiator := New().WithCharsets(CharsetLatin1)
+
+	req := newProblemRequest("application/problem+json")
+	req.Header.Set("Accept-Charset", "utf-16")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Error(recorder, req, errors.New("boom"))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, `{"title":"Internal Server Error","status":500}`+"\n", recorder.Body.String())
+}
+
+func TestErrorShouldSendProblemWithoutExtensionsThatCannotBeMarshalled(t *testing.T) {
+	var formatTests = []struct {
+		accept   string
+		expected string
+	}{
+		{"application/problem+json", `{"title":"Not Found","status":404}` + "\n"},
+		{"application/problem+xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<problem xmlns="urn:ietf:rfc:7807"><title>Not Found</title><status>404</status></problem>` + "\n"},
+	}
+
+	for _, tt := range formatTests {
+		recorder := httptest.NewRecorder()
+		err := New().Error(recorder, newProblemRequest(tt.accept), unmarshallableProblem{})
+
+		assert.Error(t, err, tt.accept)
+		assert.Equal(t, http.StatusNotFound, recorder.Code, tt.accept)
+		assert.Equal(t, tt.accept, recorder.HeaderMap.Get("Content-Type"), tt.accept)
+		assert.Equal(t, tt.expected, recorder.Body.String(), tt.accept)
+	}
+}
+
+// unmarshallableProblem has an extension that neither JSON nor XML can represent.
+type unmarshallableProblem struct{}
+
+func (e unmarshallableProblem) Error() string {
+	return "not found"
+}
+
+func (e unmarshallableProblem) ProblemDetails() ProblemDetails {
+	return ProblemDetails{Status: http.StatusNotFound, Extensions: map[string]interface{}{"retry": func() {}}}
+}
+
+func TestErrorShouldDeclareTheNegotiatedCharsetInXML(t *testing.T) {
+	negotiator := New().WithCharsets(CharsetUTF8, CharsetLatin1)
+
+	req := newProblemRequest("application/problem+xml")
+	req.Header.Set("Accept-Charset", "iso-8859-1")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Error(recorder, req, errors.New("boom"))
+
+	assert.Equal(t, "application/problem+xml; charset=iso-8859-1", recorder.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="iso-8859-1"?>`+"\n"+
+		`<problem xmlns="urn:ietf:rfc:7807"><title>Internal Server Error</title><status>500</status></problem>`+"\n",
+		recorder.Body.String())
+}
+
+// failingWriter is a ResponseWriter whose connection has gone.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestErrorShouldBeBufferedAndReportFailuresToTheHook(t *testing.T) {
+	var hooked error
+	negotiator := New().WithBuffering(true).WithErrorHook(func(req *http.Request, err error) { hooked = err })
+	recorder := failingWriter{httptest.NewRecorder()}
+
+	err := negotiator.Error(recorder, newProblemRequest("application/problem+json"), errors.New("boom"))
+
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, err, hooked)
+	assert.Equal(t, "47", recorder.HeaderMap.Get("Content-Length"))
+}
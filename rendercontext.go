@@ -0,0 +1,44 @@
+package negotiator
+
+// RenderContext describes the negotiated response to a ContextProcessor.
+type RenderContext struct {
+	// MediaRange is the client's media range that selected the processor, with its
+	// parameters. It is the zero value for Ajax requests and fallbacks.
+	MediaRange MediaRange
+	// MediaType is the declared media type that was chosen, with its parameters, or
+	// empty if the processor was chosen by its CanProcess method.
+	MediaType string
+	// Language, Charset and Encoding are those chosen for the response, if any. The
+	// processor writes UTF-8 and uncompressed output whatever they are; the Negotiator
+	// transcodes and compresses it.
+	Language, Charset, Encoding string
+	// Status is the status with which the response is sent, or 0 if it is the
+	// processor's to choose.
+	Status int
+	// Template is the template given in the ResponseOptions, if any.
+	Template string
+	// Args holds the context arguments given to Negotiate or Respond.
+	Args []interface{}
+
+	values map[interface{}]interface{}
+}
+
+func newRenderContext(d Decision, status int, options ResponseOptions, args []interface{}) *RenderContext {
+	return &RenderContext{
+		MediaRange: d.MediaRange,
+		MediaType:  d.MediaType,
+		Language:   d.Language,
+		Charset:    d.Charset,
+		Encoding:   d.Encoding,
+		Status:     status,
+		Template:   options.Template,
+		Args:       args,
+		values:     options.Values,
+	}
+}
+
+// Value returns the value given for the key in the ResponseOptions, or nil if there is
+// none.
+func (rc *RenderContext) Value(key interface{}) interface{} {
+	return rc.values[key]
+}
@@ -0,0 +1,89 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type themeKey struct{}
+
+type fakeContextProcessor struct {
+	fakeOfferProcessor
+	rc *RenderContext
+}
+
+func (p *fakeContextProcessor) ProcessWithContext(w http.ResponseWriter, req *http.Request, model interface{}, rc *RenderContext) error {
+	p.rc = rc
+	w.Header().Set("Content-Type", rc.MediaType)
+	w.Write([]byte(rc.Template))
+	return nil
+}
+
+func TestContextProcessorShouldReceiveRenderContext(t *testing.T) {
+	html := &fakeContextProcessor{fakeOfferProcessor: fakeOfferProcessor{mediaTypes: []string{"text/html;level=1"}}}
+	negotiator := New(html).WithLanguages("en", "de").WithCharsets(CharsetUTF8)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/html;level=1;q=0.9")
+	req.Header.Set("Accept-Language", "de")
+	recorder := httptest.NewRecorder()
+
+	err := negotiator.Respond(recorder, req, ResponseOptions{
+		Status:   http.StatusCreated,
+		Template: "user.html",
+		Values:   map[interface{}]interface{}{themeKey{}: "dark"},
+	}, "Joe", "legacy")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user.html", recorder.Body.String())
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	rc := html.rc
+	assert.Equal(t, "text/html;level=1", rc.MediaRange.String())
+	assert.Equal(t, 0.9, rc.MediaRange.Q)
+	assert.Equal(t, "text/html;level=1", rc.MediaType)
+	assert.Equal(t, "de", rc.Language)
+	assert.Equal(t, CharsetUTF8, rc.Charset)
+	assert.Equal(t, http.StatusCreated, rc.Status)
+	assert.Equal(t, []interface{}{"legacy"}, rc.Args)
+	assert.Equal(t, "dark", rc.Value(themeKey{}))
+	assert.Nil(t, rc.Value("missing"))
+}
+
+func TestContextProcessorShouldGetModelStatusFromNegotiate(t *testing.T) {
+	processor := &fakeContextProcessor{fakeOfferProcessor: fakeOfferProcessor{mediaTypes: []string{"application/json"}}}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	New(processor).Negotiate(recorder, req, unknownFieldError("email"))
+
+	assert.Equal(t, http.StatusBadRequest, processor.rc.Status)
+	assert.Equal(t, "", processor.rc.Template)
+	assert.Nil(t, processor.rc.Args)
+}
+
+type fakeArgsProcessor struct {
+	fakeProcessor
+	args []interface{}
+}
+
+func (p *fakeArgsProcessor) Process(w http.ResponseWriter, req *http.Request, model interface{}, context ...interface{}) error {
+	p.args = context
+	return nil
+}
+
+func TestProcessorsWithoutContextShouldStillReceiveArgs(t *testing.T) {
+	processor := &fakeArgsProcessor{}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/negotiatortesting")
+	recorder := httptest.NewRecorder()
+
+	New(processor).Negotiate(recorder, req, "foo", "bar")
+
+	assert.Equal(t, []interface{}{"bar"}, processor.args)
+}
@@ -0,0 +1,113 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondShouldApplyStatusAndHeaders(t *testing.T) {
+	var processorTests = []struct {
+		processor   ResponseProcessor
+		accept      string
+		contentType string
+	}{
+		{NewJSON(), "application/json", "application/json"},
+		{NewXML(), "application/xml", "application/xml"},
+		{NewTXT(), "text/plain", "text/plain"},
+		{NewCSV(), "text/csv", "text/csv"},
+	}
+
+	for _, tt := range processorTests {
+		req, _ := http.NewRequest("POST", "/users", nil)
+		req.Header.Set("Accept", tt.accept)
+		recorder := httptest.NewRecorder()
+
+		err := New(tt.processor).Respond(recorder, req, ResponseOptions{
+			Status: http.StatusCreated,
+			Header: http.Header{"Location": {"/users/42"}, "Content-Type": {"application/octet-stream"}},
+		}, "Joe Bloggs")
+
+		assert.NoError(t, err, tt.accept)
+		assert.Equal(t, http.StatusCreated, recorder.Code, tt.accept)
+		assert.Equal(t, "/users/42", recorder.HeaderMap.Get("Location"), tt.accept)
+		assert.Equal(t, tt.contentType, recorder.HeaderMap.Get("Content-Type"), tt.accept)
+		assert.NotEmpty(t, recorder.Body.String(), tt.accept)
+	}
+}
+
+func TestRespondShouldOverrideNoContentForNilModel(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/jobs", nil)
+	recorder := httptest.NewRecorder()
+
+	Respond(recorder, req, ResponseOptions{Status: http.StatusAccepted}, nil)
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestRespondShouldNotRenderModelForStatusWithoutBody(t *testing.T) {
+	var statusTests = []struct {
+		status   int
+		buffered bool
+	}{
+		{http.StatusNoContent, false},
+		{http.StatusNotModified, false},
+		{http.StatusNotModified, true},
+		{http.StatusEarlyHints, false},
+	}
+
+	for _, tt := range statusTests {
+		req, _ := http.NewRequest("GET", "/users/42", nil)
+		recorder := httptest.NewRecorder()
+
+		err := New(NewJSON()).WithBuffering(tt.buffered).WithCompression(0).Respond(recorder, req, ResponseOptions{
+			Status: tt.status,
+			Header: http.Header{"Etag": {`"v1"`}},
+		}, "Joe Bloggs")
+
+		assert.NoError(t, err, tt.status)
+		assert.Equal(t, tt.status, recorder.Code, tt.status)
+		assert.Equal(t, `"v1"`, recorder.HeaderMap.Get("Etag"), tt.status)
+		assert.Empty(t, recorder.HeaderMap.Get("Content-Type"), tt.status)
+		assert.Empty(t, recorder.HeaderMap.Get("Content-Encoding"), tt.status)
+		assert.Empty(t, recorder.Body.String(), tt.status)
+	}
+}
+
+func TestRespondShouldPreferOptionsToModelStatus(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	New(NewJSON()).Respond(recorder, req, ResponseOptions{Status: http.StatusConflict}, unknownFieldError("email"))
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestRespondShouldNotApplyOptionsWhenNotAcceptable(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+
+	New(NewJSON()).Respond(recorder, req, ResponseOptions{
+		Status: http.StatusCreated,
+		Header: http.Header{"Location": {"/users/42"}},
+	}, "Joe")
+
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	assert.Empty(t, recorder.HeaderMap.Get("Location"))
+}
+
+func TestRespondShouldWorkWithCompression(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+
+	New(NewJSON()).WithCompression(0).Respond(recorder, req, ResponseOptions{Status: http.StatusCreated}, "Joe")
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "gzip", recorder.HeaderMap.Get("Content-Encoding"))
+	assert.Equal(t, "\"Joe\"\n", decompress(t, "gzip", recorder.Body))
+}
@@ -9,7 +9,10 @@ type ResponseProcessor interface {
 }
 
 // ContentTypeSettable interface provides for those response processors that allow the
-// response Content-Type to be set explicitly.
+// response Content-Type to be set explicitly. SetContentType returns a new processor
+// with the Content-Type, leaving the original unchanged, so that a processor shared
+// between goroutines is never modified. The built-in processors are immutable in this
+// way and safe for concurrent use.
 type ContentTypeSettable interface {
 	SetContentType(contentType string) ResponseProcessor
 }
@@ -21,3 +24,48 @@ type ContentTypeSettable interface {
 type AjaxResponseProcessor interface {
 	IsAjaxResponder() bool
 }
+
+// MediaRangeProcessor interface allows a ResponseProcessor to inspect each parsed
+// media range, including its parameters, rather than the flattened string given
+// to CanProcess. If a ResponseProcessor also implements this interface, its
+// CanProcessMediaRange method is used instead of CanProcess during negotiation.
+type MediaRangeProcessor interface {
+	CanProcessMediaRange(mediaRange MediaRange) bool
+}
+
+// MediaTypeProcessor interface allows a ResponseProcessor to declare the concrete media
+// types it produces, such as "application/json". The Negotiator matches media ranges,
+// including wildcards and parameters, against these declared media types itself.
+// Concrete media ranges are still passed to CanProcess as well, which allows a processor
+// to accept open-ended families such as "+json" that it cannot list. Processors that do
+// not implement this interface are matched by CanProcess alone.
+//
+// A declared media type may carry a qs parameter giving the server quality of that
+// representation, as in "application/xml;qs=0.8"; it is not used for matching.
+type MediaTypeProcessor interface {
+	MediaTypes() []string
+}
+
+// LanguageProcessor interface allows a ResponseProcessor to declare the languages it can
+// render, such as "en" or "de-CH", when they differ from those given to WithLanguages;
+// an empty list means its output has no language. It is consulted in UnifiedMode only.
+type LanguageProcessor interface {
+	Languages() []string
+}
+
+// RequestProcessor interface creates the contract for decoding request bodies, the
+// counterpart of ResponseProcessor. CanDecode is given the media type of the request's
+// Content-Type in lower case, without parameters; Decode reads the body into the
+// dataModel, which is normally a pointer. A RequestProcessor may also implement
+// MediaTypeProcessor to list the media types it decodes, for error responses.
+type RequestProcessor interface {
+	CanDecode(mediaType string) bool
+	Decode(req *http.Request, dataModel interface{}) error
+}
+
+// ContextProcessor interface gives a ResponseProcessor the outcome of negotiation in a
+// typed RenderContext. If a ResponseProcessor also implements this interface, its
+// ProcessWithContext method is used instead of Process.
+type ContextProcessor interface {
+	ProcessWithContext(w http.ResponseWriter, req *http.Request, dataModel interface{}, rc *RenderContext) error
+}
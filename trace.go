@@ -0,0 +1,101 @@
+package negotiator
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// traceHeader is the response header that carries the trace in debug builds.
+const traceHeader = "X-Negotiation-Trace"
+
+// TraceEntry records how one representation fared against one of the client's media
+// ranges during negotiation.
+type TraceEntry struct {
+	// Processor is the position of the response processor.
+	Processor int
+	// MediaType is the processor's declared media type, or empty where the processor's
+	// CanProcess method was asked instead.
+	MediaType string
+	// MediaRange is the client's media range.
+	MediaRange string
+	// Matched is true if the media range selects the representation.
+	Matched bool
+	// Decisive is true if this is the media range that gives the representation its
+	// quality: the most specific match in RFC9110Mode and UnifiedMode, or the first in
+	// LegacyMode.
+	Decisive bool
+	// Q is the client's quality for the media range (its weight, in LegacyMode), QS the
+	// server quality of the representation and Score their product.
+	Q, QS, Score float64
+	// Chosen is true for the one entry that decided the outcome of negotiation.
+	Chosen bool
+}
+
+func (e TraceEntry) String() string {
+	mediaType := e.MediaType
+	if mediaType == "" {
+		mediaType = "CanProcess"
+	}
+
+	s := fmt.Sprintf("processor=%d offer=%s range=%s", e.Processor, mediaType, e.MediaRange)
+	switch {
+	case !e.Matched:
+		return s + " no-match"
+	case !e.Decisive:
+		return s + " match superseded"
+	case e.Chosen:
+		return s + fmt.Sprintf(" q=%g qs=%g score=%g chosen", e.Q, e.QS, e.Score)
+	}
+	return s + fmt.Sprintf(" q=%g qs=%g score=%g", e.Q, e.QS, e.Score)
+}
+
+// WithTrace turns tracing on or off. When it is on, the Decision records every
+// comparison made during negotiation in its Trace, and its Reasons. In builds with the
+// negotiatordebug build tag, Negotiate also writes the trace to the X-Negotiation-Trace
+// response header, one entry per field line; production builds never do. A new
+// Negotiator is returned with the same processors as the original.
+func (n *Negotiator) WithTrace(enabled bool) *Negotiator {
+	return n.With(WithTrace(enabled))
+}
+
+// traceVariant records the comparisons of the variant with each media range, where r
+// is the index of the decisive range.
+func traceVariant(trace *[]TraceEntry, v variant, ranges []MediaRange, r int, mode Mode) {
+	mediaType := ""
+	if v.offer != nil {
+		mediaType = v.offer.String()
+	}
+
+	for j, mr := range ranges {
+		e := TraceEntry{
+			Processor:  v.index,
+			MediaType:  mediaType,
+			MediaRange: legacyValue(mr),
+			Matched:    j == r || v.matches(mr),
+			Decisive:   j == r,
+			Q:          mr.Q,
+			QS:         v.qs,
+		}
+		if mode == LegacyMode {
+			e.Q = legacyWeight(mr)
+		}
+		if e.Decisive {
+			e.Score = e.Q * e.QS
+		}
+		*trace = append(*trace, e)
+	}
+}
+
+// writeTrace writes the trace to the response header, in debug builds only.
+func writeTrace(header http.Header, d Decision) {
+	if !traceHeaderEnabled {
+		return
+	}
+
+	for _, e := range d.Trace {
+		header.Add(traceHeader, e.String())
+	}
+	for _, reason := range d.Reasons {
+		header.Add(traceHeader, reason)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build negotiatordebug
+// +build negotiatordebug
+
+package negotiator
+
+// traceHeaderEnabled allows the trace to be written to responses. This build has the
+// negotiatordebug tag, so it is on.
+const traceHeaderEnabled = true
@@ -0,0 +1,8 @@
+//go:build !negotiatordebug
+// +build !negotiatordebug
+
+package negotiator
+
+// traceHeaderEnabled allows the trace to be written to responses. It is off unless the
+// negotiatordebug build tag is given.
+const traceHeaderEnabled = false
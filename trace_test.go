@@ -0,0 +1,60 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceShouldRecordEveryComparison(t *testing.T) {
+	negotiator := New(NewJSON(), &fakeProcessor{}).WithMode(RFC9110Mode).WithTrace(true)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/*;q=0.5, application/json;q=0.4, */*;q=0.1")
+
+	d, _ := negotiator.Decide(req)
+
+	assert.Equal(t, []TraceEntry{
+		{0, "application/json", "application/*", true, false, 0.5, 1, 0, false},
+		{0, "application/json", "application/json", true, true, 0.4, 1, 0.4, true},
+		{0, "application/json", "*/*", true, false, 0.1, 1, 0, false},
+		{0, "", "application/*", false, false, 0.5, 1, 0, false},
+		{0, "", "application/json", true, true, 0.4, 1, 0.4, false},
+		{0, "", "*/*", false, false, 0.1, 1, 0, false},
+		{1, "", "application/*", false, false, 0.5, 1, 0, false},
+		{1, "", "application/json", false, false, 0.4, 1, 0, false},
+		{1, "", "*/*", true, true, 0.1, 1, 0.1, false},
+	}, d.Trace)
+}
+
+func TestTraceShouldBeOffByDefault(t *testing.T) {
+	negotiator := New(NewJSON())
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/json")
+
+	d, _ := negotiator.Decide(req)
+
+	assert.Nil(t, d.Trace)
+}
+
+func TestTraceEntryString(t *testing.T) {
+	assert.Equal(t, "processor=0 offer=application/json range=application/json q=0.4 qs=1 score=0.4 chosen",
+		TraceEntry{0, "application/json", "application/json", true, true, 0.4, 1, 0.4, true}.String())
+	assert.Equal(t, "processor=1 offer=CanProcess range=text/* no-match",
+		TraceEntry{Processor: 1, MediaRange: "text/*"}.String())
+}
+
+func TestTraceHeaderOnlyInDebugBuilds(t *testing.T) {
+	negotiator := New(NewJSON()).WithTrace(true)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, traceHeaderEnabled, len(recorder.HeaderMap[traceHeader]) > 0)
+}
@@ -0,0 +1,50 @@
+package negotiator
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type txtDecoder struct{}
+
+// NewTXTDecoder creates a request processor for text/plain. Model values should be one of
+// the following:
+//
+// * *string
+//
+// * *[]byte
+//
+// * encoding.TextUnmarshaler
+func NewTXTDecoder() RequestProcessor {
+	return &txtDecoder{}
+}
+
+// Implements MediaTypeProcessor for this type.
+func (*txtDecoder) MediaTypes() []string {
+	return []string{defaultTxtContentType}
+}
+
+func (*txtDecoder) CanDecode(mediaType string) bool {
+	return mediaType == "text/plain"
+}
+
+func (*txtDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	switch v := dataModel.(type) {
+	case *string:
+		*v = string(body)
+	case *[]byte:
+		*v = body
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(body)
+	default:
+		return fmt.Errorf("Unsupported type for TXT: %T", dataModel)
+	}
+	return nil
+}
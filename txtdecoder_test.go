@@ -0,0 +1,29 @@
+package negotiator
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTXTDecoderShouldDecodeModels(t *testing.T) {
+	var s string
+	assert.NoError(t, NewTXTDecoder().Decode(newBodyRequest("text/plain", "Joe Bloggs"), &s))
+	assert.Equal(t, "Joe Bloggs", s)
+
+	var b []byte
+	assert.NoError(t, NewTXTDecoder().Decode(newBodyRequest("text/plain", "Joe Bloggs"), &b))
+	assert.Equal(t, []byte("Joe Bloggs"), b)
+
+	var ip net.IP
+	assert.NoError(t, NewTXTDecoder().Decode(newBodyRequest("text/plain", "10.0.0.1"), &ip))
+	assert.Equal(t, "10.0.0.1", ip.String())
+}
+
+func TestTXTDecoderShouldRejectUnsupportedModel(t *testing.T) {
+	var n int
+	err := NewTXTDecoder().Decode(newBodyRequest("text/plain", "1"), &n)
+
+	assert.EqualError(t, err, "Unsupported type for TXT: *int")
+}
@@ -25,10 +25,17 @@ func NewTXT() ResponseProcessor {
 	return &txtProcessor{defaultTxtContentType}
 }
 
-// Implements ContentTypeSettable for this type.
+// Implements ContentTypeSettable for this type. A copy of the processor is returned; the
+// original is unchanged.
 func (p *txtProcessor) SetContentType(contentType string) ResponseProcessor {
-	p.contentType = contentType
-	return p
+	p2 := *p
+	p2.contentType = contentType
+	return &p2
+}
+
+// Implements MediaTypeProcessor for this type.
+func (p *txtProcessor) MediaTypes() []string {
+	return []string{p.contentType}
 }
 
 func (*txtProcessor) CanProcess(mediaRange string) bool {
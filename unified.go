@@ -0,0 +1,159 @@
+package negotiator
+
+import "net/http"
+
+// Score is the breakdown of the overall score of a representation in UnifiedMode. A
+// dimension that the Negotiator does not negotiate scores 1.
+type Score struct {
+	// MediaType is the client's quality for the media type, and Server the server
+	// quality of the processor and its media type.
+	MediaType, Server float64
+	// Language, Charset and Encoding are the client's qualities for the language,
+	// charset and content coding.
+	Language, Charset, Encoding float64
+	// Total is the product of all the others.
+	Total float64
+}
+
+// decideUnified chooses the processor, language, charset and content coding together,
+// for UnifiedMode. Only the language can differ between processors (see
+// LanguageProcessor), so the best charset and content coding are the same for every
+// processor and are chosen first; the charset counts only for media types that are
+// transcoded. If no processor is acceptable in a language it can render, the language
+// is chosen by lookup for the benefit of the fallback processor. As in the other modes,
+// false is returned if no charset or no content coding is acceptable.
+func (n *Negotiator) decideUnified(req *http.Request) (d Decision, charsetOK, encodingOK bool) {
+	d = Decision{Index: -1, Vary: make([]string, 0, varyCapacity), explain: n.explains()}
+
+	var candidates []scoredVariant
+	var ranges []MediaRange
+	var buf [acceptBufferSize]MediaRange
+	var scored [acceptBufferSize]scoredVariant
+	if ajax := n.ajaxResponder(); ajax >= 0 {
+		d.Vary = append(d.Vary, xRequestedWith)
+		if IsAjax(req) {
+			v := variant{index: ajax, processor: n.processors[ajax], qs: 1.0}
+			candidates = []scoredVariant{{variant: v, rangeIndex: -1, q: 1.0, score: 1.0, entry: -1}}
+			if d.explain {
+				d.reason("Ajax request; only Ajax responder %d is considered", ajax)
+			}
+		}
+	}
+
+	if candidates == nil && len(n.processors) > 0 {
+		ranges = n.mediaRanges(req, &d, buf[:0])
+
+		var trace *[]TraceEntry
+		if n.trace {
+			trace = &d.Trace
+		}
+		candidates = scoreVariants(scored[:0], n.variants, ranges, n.mode, trace)
+	}
+
+	acceptLanguage := combinedHeader(req.Header, "Accept-Language")
+	if n.negotiatesLanguage() {
+		d.Vary = append(d.Vary, "Accept-Language")
+	}
+
+	charset, qc := n.bestCharset(req, &d)
+	var qe float64
+	d.Encoding, qe = n.bestEncoding(req, &d)
+	if qe == 0 {
+		d.reason("no acceptable charset or encoding")
+		return d, qc > 0, false
+	}
+
+	var chosen scoredVariant
+	found := false
+	for _, c := range candidates {
+		cq := qc
+		if len(n.charsets) > 0 && !transcodes(c.processor, offerType(c.offer)) {
+			cq = 1.0
+		}
+		if cq == 0 {
+			continue
+		}
+
+		language, ql := bestLanguage(acceptLanguage, n.languagesOf(c.processor))
+		if ql == 0 {
+			continue
+		}
+
+		s := Score{MediaType: c.q, Server: c.qs, Language: ql, Charset: cq, Encoding: qe}
+		s.Total = c.score * ql * cq * qe
+		if !found || s.Total > d.Score.Total || (s.Total == d.Score.Total && c.rangeIndex < chosen.rangeIndex) {
+			chosen, d.Language, d.Score = c, language, s
+			found = true
+		}
+	}
+
+	if !found {
+		if qc == 0 {
+			d.reason("no acceptable charset or encoding")
+			return d, false, true
+		}
+		if len(n.languages) > 0 {
+			d.Language = LookupLanguage(acceptLanguage, n.languages[0], n.languages...)
+		}
+		d.reason("no representation is acceptable")
+		return d, true, true
+	}
+
+	if chosen.entry >= 0 {
+		d.Trace[chosen.entry].Chosen = true
+	}
+
+	d.Processor, d.Index, d.Quality = chosen.processor, chosen.index, chosen.score
+	if chosen.rangeIndex >= 0 {
+		d.MediaRange = ranges[chosen.rangeIndex].withParamMaps()
+	}
+	if chosen.offer != nil {
+		d.MediaType = chosen.offer.String()
+	}
+	if charset != "" && transcodes(d.Processor, d.MediaType) {
+		d.Charset = charset
+	}
+	if d.explain {
+		d.reason("chose processor %d with score %g: media type q=%g, qs=%g, language %q q=%g, charset q=%g, encoding q=%g",
+			d.Index, d.Score.Total, d.Score.MediaType, d.Score.Server, d.Language, d.Score.Language,
+			d.Score.Charset, d.Score.Encoding)
+	}
+	return d, true, true
+}
+
+// negotiatesLanguage tests whether any processor can render more than one language.
+func (n *Negotiator) negotiatesLanguage() bool {
+	if len(n.languages) > 0 {
+		return true
+	}
+	for _, p := range n.processors {
+		if lp, ok := p.(LanguageProcessor); ok && len(lp.Languages()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// languagesOf returns the languages the processor can render.
+func (n *Negotiator) languagesOf(p ResponseProcessor) []string {
+	if lp, ok := p.(LanguageProcessor); ok {
+		return lp.Languages()
+	}
+	return n.languages
+}
+
+// bestLanguage returns the language tag with the highest quality, the first winning
+// ties, or the empty string with quality 1 if there are no tags.
+func bestLanguage(acceptLanguage string, tags []string) (string, float64) {
+	if len(tags) == 0 {
+		return "", 1.0
+	}
+
+	best, bestQ := "", 0.0
+	for _, tag := range tags {
+		if q := languageQuality(acceptLanguage, tag); q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best, bestQ
+}
@@ -0,0 +1,180 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTranslatedProcessor struct {
+	fakeOfferProcessor
+	languages []string
+}
+
+func (p *fakeTranslatedProcessor) Languages() []string {
+	return p.languages
+}
+
+func TestUnifiedModeShouldPreferRepresentationAcceptableOnEveryDimension(t *testing.T) {
+	html := &fakeTranslatedProcessor{fakeOfferProcessor{[]string{"text/html"}, "html"}, []string{"en"}}
+	json := &fakeTranslatedProcessor{fakeOfferProcessor{[]string{"application/json"}, "json"}, []string{"en", "de"}}
+	negotiator := New(html, json).WithMode(UnifiedMode)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "text/html, application/json;q=0.8")
+	req.Header.Add("Accept-Language", "de")
+	recorder := httptest.NewRecorder()
+
+	negotiator.Negotiate(recorder, req, "foo")
+
+	assert.Equal(t, "json", recorder.Body.String())
+	assert.Equal(t, "de", recorder.HeaderMap.Get("Content-Language"))
+	assert.Equal(t, "Accept, Accept-Language", recorder.HeaderMap.Get("Vary"))
+}
+
+func TestUnifiedModeShouldExposeScoreBreakdown(t *testing.T) {
+	negotiator := New(NewJSON(), NewXML()).
+		WithMode(UnifiedMode).
+		WithLanguages("en", "de").
+		WithCharsets(CharsetUTF8, CharsetLatin1).
+		WithCompression(0)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept", "application/xml;q=0.5, application/json;q=0.4")
+	req.Header.Add("Accept-Language", "de-CH;q=0.9, en;q=0.3")
+	req.Header.Add("Accept-Charset", "iso-8859-1, utf-8;q=0.7")
+	req.Header.Add("Accept-Encoding", "gzip;q=0.5, identity")
+
+	d, err := negotiator.Decide(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.Index)
+	assert.Equal(t, "de", d.Language)
+	assert.Equal(t, CharsetLatin1, d.Charset)
+	assert.Equal(t, EncodingIdentity, d.Encoding)
+	assert.Equal(t, Score{MediaType: 0.5, Server: 1, Language: 0.9, Charset: 1, Encoding: 1, Total: 0.45}, d.Score)
+	assert.Equal(t, []string{"X-Requested-With", "Accept", "Accept-Language", "Accept-Charset", "Accept-Encoding"}, d.Vary)
+}
+
+func TestUnifiedModeShouldRejectRefusedLanguage(t *testing.T) {
+	negotiator := New(NewJSON()).WithMode(UnifiedMode).WithLanguages("en")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Language", "en;q=0")
+
+	d, err := negotiator.Decide(req)
+
+	assert.True(t, errors.Is(err, ErrNotAcceptable))
+	assert.Nil(t, d.Processor)
+}
+
+func TestUnifiedModeShouldFallBackToDefaultLanguage(t *testing.T) {
+	negotiator := New(NewJSON()).WithMode(UnifiedMode).WithLanguages("en", "de")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Language", "fr")
+
+	d, err := negotiator.Decide(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "en", d.Language)
+	assert.Equal(t, unlistedLanguageQuality, d.Score.Language)
+}
+
+func TestUnifiedModeShouldReportUnacceptableCharset(t *testing.T) {
+	negotiator := New(NewXML()).WithMode(UnifiedMode).WithCharsets(CharsetUTF8)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Charset", "iso-8859-1")
+
+	_, err := negotiator.Decide(req)
+
+	var notAcceptable *NotAcceptableError
+	assert.True(t, errors.As(err, &notAcceptable))
+	assert.Equal(t, []string{CharsetUTF8}, notAcceptable.Charsets)
+}
+
+func TestUnifiedModeShouldAgreeWithRFC9110ModeOnMediaType(t *testing.T) {
+	var acceptTests = []string{
+		"",
+		"*/*",
+		"text/csv",
+		"text/*, application/json",
+		"application/xml;q=0.5, application/json;q=0.4",
+		"application/*;q=0.5, application/json;q=0",
+	}
+
+	unified := New(NewJSON(), NewXML(), NewCSV()).WithMode(UnifiedMode)
+	rfc9110 := New(NewJSON(), NewXML(), NewCSV()).WithMode(RFC9110Mode)
+
+	for _, accept := range acceptTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add("Accept", accept)
+
+		d1, _ := unified.Decide(req)
+		d2, _ := rfc9110.Decide(req)
+
+		assert.Equal(t, d2.Index, d1.Index, accept)
+		assert.Equal(t, d2.Quality, d1.Score.Total, accept)
+	}
+}
+
+func TestUnifiedModeShouldAgreeWithOtherModesOnCharsetAndEncoding(t *testing.T) {
+	var headerTests = []struct {
+		acceptCharset, acceptEncoding string
+		charset, encoding             string
+	}{
+		{"", "", CharsetUTF8, EncodingIdentity},
+		{"iso-8859-1, utf-8", "gzip, deflate", CharsetUTF8, EncodingGzip},
+		{"iso-8859-1, utf-8;q=0.9", "gzip, deflate", CharsetLatin1, EncodingGzip},
+		{"utf-8;q=0.5, *", "deflate, gzip", CharsetLatin1, EncodingGzip},
+		{"koi8-r, *;q=0.1", "gzip;q=0.5, identity", CharsetUTF8, EncodingIdentity},
+		{"", "br", CharsetUTF8, EncodingIdentity},
+		{"", "deflate, identity;q=0", CharsetUTF8, EncodingDeflate},
+	}
+
+	for _, mode := range []Mode{LegacyMode, RFC9110Mode, UnifiedMode} {
+		negotiator := New(NewTXT()).WithMode(mode).WithCharsets(CharsetUTF8, CharsetLatin1).WithCompression(0)
+
+		for _, tt := range headerTests {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Charset", tt.acceptCharset)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			d, err := negotiator.Decide(req)
+
+			assert.NoError(t, err, tt.acceptCharset+" "+tt.acceptEncoding)
+			assert.Equal(t, tt.charset, d.Charset, tt.acceptCharset)
+			assert.Equal(t, tt.encoding, d.Encoding, tt.acceptEncoding)
+		}
+	}
+}
+
+func TestLanguageQuality(t *testing.T) {
+	var qualityTests = []struct {
+		acceptLanguage string
+		tag            string
+		expected       float64
+	}{
+		{"", "en", 1},
+		{"en", "en-GB", 1},
+		{"en;q=0.5, en-GB;q=0.8", "en-GB", 0.8},
+		{"de-CH;q=0.7", "de", 0.7},
+		{"de-CH;q=0.7, *;q=0.2", "de", 0.7},
+		{"*;q=0.2", "fr", 0.2},
+		{"en, *;q=0", "fr", 0},
+		{"en", "fr", unlistedLanguageQuality},
+		{"fr;q=0", "fr", 0},
+		{"de-CH, de;q=0", "de", 0},
+		{"de-CH, de;q=0", "de-CH", 1},
+		{"de-CH-1996, de-CH;q=0", "de", 1},
+		{"de-CH, *;q=0", "de", 1},
+	}
+
+	for _, tt := range qualityTests {
+		assert.Equal(t, tt.expected, languageQuality(tt.acceptLanguage, tt.tag), tt.acceptLanguage)
+	}
+}
@@ -0,0 +1,57 @@
+package negotiator
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithVary adds request headers to the Vary response header, beyond those the Negotiator
+// consults itself. Use it when a processor's output depends on other request headers.
+// A new Negotiator is returned with the same processors as the original.
+//
+// Every response written during negotiation lists, in its Vary header, the request
+// headers that influenced the choice of representation: Accept, and X-Requested-With if
+// any processor handles Ajax requests. This allows shared caches to store the
+// representations separately.
+func (n *Negotiator) WithVary(headers ...string) *Negotiator {
+	return n.With(WithVary(headers...))
+}
+
+// addVary adds the header names to the Vary header, unless they are already listed or
+// the header is "*". The resulting Vary header has a single field value.
+func addVary(header http.Header, names ...string) {
+	if len(names) == 0 {
+		return
+	}
+
+	var tokens []string
+	for _, value := range header["Vary"] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token == "*" {
+				return
+			} else if token != "" && !containsFold(tokens, token) {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	n := len(tokens)
+	for _, name := range names {
+		if !containsFold(tokens, name) {
+			tokens = append(tokens, http.CanonicalHeaderKey(name))
+		}
+	}
+
+	if len(tokens) > n || len(header["Vary"]) > 1 {
+		header.Set("Vary", strings.Join(tokens, ", "))
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldSetVaryHeader(t *testing.T) {
+	var varyTests = []struct {
+		negotiator *Negotiator
+		ajax       bool
+		accept     string
+		expected   string
+	}{
+		{New(NewXML()), false, "application/xml", "Accept"},
+		{New(NewXML()), false, "image/png", "Accept"},
+		{New(NewXML()), true, "", "Accept"},
+		{New(NewJSON(), NewXML()), false, "application/xml", "X-Requested-With, Accept"},
+		{New(NewJSON(), NewXML()), true, "application/xml", "X-Requested-With"},
+		{New(NewXML()).WithVary("accept-language", "Accept"), false, "", "Accept, Accept-Language"},
+	}
+
+	for _, tt := range varyTests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if tt.ajax {
+			req.Header.Add(xRequestedWith, xmlHttpRequest)
+		}
+		if tt.accept != "" {
+			req.Header.Add("Accept", tt.accept)
+		}
+		recorder := httptest.NewRecorder()
+
+		tt.negotiator.Negotiate(recorder, req, "foo")
+
+		assert.Equal(t, tt.expected, recorder.HeaderMap.Get("Vary"), tt.accept)
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	var addTests = []struct {
+		existing []string
+		names    []string
+		expected []string
+	}{
+		{nil, nil, nil},
+		{nil, []string{"accept"}, []string{"Accept"}},
+		{[]string{"Origin"}, []string{"Accept"}, []string{"Origin, Accept"}},
+		{[]string{"accept, Origin"}, []string{"Accept"}, []string{"accept, Origin"}},
+		{[]string{"Origin", "Cookie"}, []string{"Accept"}, []string{"Origin, Cookie, Accept"}},
+		{[]string{"*"}, []string{"Accept"}, []string{"*"}},
+		{nil, []string{"Accept", "accept"}, []string{"Accept"}},
+	}
+
+	for _, tt := range addTests {
+		header := http.Header{}
+		for _, v := range tt.existing {
+			header.Add("Vary", v)
+		}
+
+		addVary(header, tt.names...)
+
+		assert.Equal(t, tt.expected, header["Vary"])
+	}
+}
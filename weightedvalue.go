@@ -1,5 +1,7 @@
 package negotiator
 
+import "sort"
+
 // WeightedValue is a value and associate weight between 0.0 and 1.0
 type weightedValue struct {
 	Value  string
@@ -13,3 +15,37 @@ type byWeight []weightedValue
 func (a byWeight) Len() int           { return len(a) }
 func (a byWeight) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byWeight) Less(i, j int) bool { return a[i].Weight > a[j].Weight }
+
+// parseWeightedList parses a header made of weighted tokens, such as Accept-Language or
+// Accept-Encoding, and orders the values by descending weight, keeping the client's order
+// for equal weights. Weights default to 1 and invalid weights are treated as 1; any
+// parameter other than q is ignored.
+func parseWeightedList(header string) []weightedValue {
+	var retVals []weightedValue
+
+	elements := listScanner{s: header, sep: ','}
+	for el, ok := elements.next(); ok; el, ok = elements.next() {
+		element := header[el.start:el.end]
+		parts := listScanner{s: element, sep: ';'}
+		first, _ := parts.next()
+		first = first.trim(element)
+		if first.start == first.end {
+			continue
+		}
+
+		wv := weightedValue{element[first.start:first.end], 1.0}
+		for part, ok := parts.next(); ok; part, ok = parts.next() {
+			part = part.trim(element)
+			name, qvalue, _, reason := parseParam(element[part.start:part.end])
+			if reason == "" && name == "q" {
+				if q, ok := parseQValue(qvalue); ok {
+					wv.Weight = q
+				}
+			}
+		}
+		retVals = append(retVals, wv)
+	}
+
+	sort.Stable(byWeight(retVals))
+	return retVals
+}
@@ -0,0 +1,60 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+type xmlDecoder struct{}
+
+// NewXMLDecoder creates a request processor for XML, including text/xml and media types
+// with the +xml suffix.
+func NewXMLDecoder() RequestProcessor {
+	return &xmlDecoder{}
+}
+
+// Implements MediaTypeProcessor for this type.
+func (*xmlDecoder) MediaTypes() []string {
+	return []string{defaultXMLContentType}
+}
+
+func (*xmlDecoder) CanDecode(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func (p *xmlDecoder) Decode(req *http.Request, dataModel interface{}) error {
+	return p.DecodeWithOptions(req, dataModel, DecodeOptions{})
+}
+
+// Implements ConfigurableRequestProcessor for this type. Unknown elements cannot be
+// detected, so DisallowUnknownFields is ignored.
+func (*xmlDecoder) DecodeWithOptions(req *http.Request, dataModel interface{}, options DecodeOptions) error {
+	decoder := xml.NewDecoder(req.Body)
+	if err := decoder.Decode(dataModel); err != nil {
+		return err
+	}
+
+	if !options.DisallowTrailingData {
+		return nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return checkEOF(token, err)
+		}
+
+		switch t := token.(type) {
+		case xml.Comment, xml.ProcInst:
+			// allowed after the root element
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return trailingDataError()
+			}
+		default:
+			return trailingDataError()
+		}
+	}
+}
@@ -30,10 +30,17 @@ func NewXMLIndent2Spaces() ResponseProcessor {
 	return NewXMLIndent("", "  ")
 }
 
-// Implements ContentTypeSettable for this type.
+// Implements ContentTypeSettable for this type. A copy of the processor is returned; the
+// original is unchanged.
 func (p *xmlProcessor) SetContentType(contentType string) ResponseProcessor {
-	p.contentType = contentType
-	return p
+	p2 := *p
+	p2.contentType = contentType
+	return &p2
+}
+
+// Implements MediaTypeProcessor for this type.
+func (p *xmlProcessor) MediaTypes() []string {
+	return []string{p.contentType}
 }
 
 func (*xmlProcessor) CanProcess(mediaRange string) bool {